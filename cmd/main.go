@@ -1,35 +1,388 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/Mixturka/rc/internal/codegen"
+	"github.com/Mixturka/rc/internal/driver"
 	"github.com/Mixturka/rc/internal/erremitter"
-	"github.com/Mixturka/rc/internal/lexer"
-	"github.com/Mixturka/rc/internal/parser"
 )
 
+const (
+	exitOk = iota
+	exitCompileErr
+	exitUsageErr
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands []command
+
+// Flags shared by all subcommands.
+var (
+	colorMode        string
+	errorFormat      string
+	errorOutput      string
+	maxErrors        int
+	warningsAsErrors bool
+	// lintFlags holds the -A, -W and -D flags in the order they were given,
+	// so that a later one overrides an earlier one for the same warning.
+	lintFlags []lintFlag
+)
+
+type lintFlag struct {
+	code  erremitter.Code
+	level erremitter.LintLevel
+}
+
+func init() {
+	commands = []command{
+		{"build", "compile a source file to an executable", runBuild},
+		{"check", "report errors without producing output", runCheck},
+		{"tokens", "print the token stream of a source file", runTokens},
+		{"ast", "print the syntax tree of a source file", runAst},
+		{"emit-c", "translate a source file to C", runEmitC},
+		{"explain", "explain an error code, e.g. rc explain E0001", runExplain},
+	}
+}
+
 func main() {
-	src := "fn main() -> i32 { return (~2-23)*3 || 2 + 3; }"
-	l := lexer.NewLexer([]rune(src))
-	tokens, err := l.Tokenize()
-	fmt.Println(tokens)
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsageErr
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return exitOk
+	case "-explain", "--explain":
+		return runExplain(args[1:])
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "rc: unknown command %q\n", args[0])
+	usage(os.Stderr)
+	return exitUsageErr
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: rc <command> [flags] <file>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// parseArgs parses the flags of a subcommand, along with the flags common to
+// all of them, and returns its single source file argument.
+func parseArgs(fs *flag.FlagSet, args []string) (string, bool) {
+	fs.StringVar(&colorMode, "color", "auto", "color diagnostics: auto, always or never")
+	fs.StringVar(&errorFormat, "error-format", "human", "diagnostics format: human, json (one object per line) or sarif")
+	fs.StringVar(&errorOutput, "error-output", "", "write diagnostics to the file at `path` instead of stderr")
+	fs.IntVar(&maxErrors, "max-errors", 20, "stop after this many errors, 0 for no limit")
+	fs.BoolVar(&warningsAsErrors, "Werror", false, "treat warnings as errors")
+	fs.Func("A", "allow the warning `name` or code, e.g. unused-variable", lintLevelFlag(erremitter.Allow))
+	fs.Func("W", "report the warning `name` or code as a warning", lintLevelFlag(erremitter.Warn))
+	fs.Func("D", "report the warning `name` or code as an error", lintLevelFlag(erremitter.Deny))
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: rc %s [flags] <file>\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", false
+	}
+	switch colorMode {
+	case "auto", "always", "never":
+	default:
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -color: want auto, always or never\n", colorMode)
+		fs.Usage()
+		return "", false
+	}
+	switch errorFormat {
+	case "human", "json", "sarif":
+	default:
+		fmt.Fprintf(fs.Output(), "invalid value %q for flag -error-format: want human, json or sarif\n", errorFormat)
+		fs.Usage()
+		return "", false
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", false
+	}
+
+	return fs.Arg(0), true
+}
+
+func lintLevelFlag(level erremitter.LintLevel) func(string) error {
+	return func(name string) error {
+		code, ok := erremitter.LintCode(name)
+		if !ok {
+			return fmt.Errorf("unknown warning %q", name)
+		}
+		lintFlags = append(lintFlags, lintFlag{code, level})
+		return nil
+	}
+}
+
+func load(path string) (*driver.Compilation, bool) {
+	c, err := driver.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rc: %v\n", err)
+		return nil, false
+	}
+
+	c.ErrEmitter.SetMaxErrors(maxErrors)
+	c.ErrEmitter.SetWarningsAsErrors(warningsAsErrors)
+	for _, lf := range lintFlags {
+		c.ErrEmitter.SetLintLevel(lf.code, lf.level)
+	}
+
+	return c, true
+}
+
+// report prints the outcome of a pipeline stage and converts it into an
+// exit code. Errors, including warnings promoted to errors, make it fail.
+// Diagnostics go to stderr, or to the file given with -error-output, which
+// keeps a JSON or SARIF log apart from the output of the C compiler.
+func report(c *driver.Compilation, err error) int {
+	out := os.Stderr
+	if errorOutput != "" {
+		f, ferr := os.Create(errorOutput)
+		if ferr != nil {
+			fmt.Fprintf(os.Stderr, "rc: %v\n", ferr)
+			return exitCompileErr
+		}
+		out = f
+	}
+
+	rep := reporter(c, out)
+	rep.Report(out, c.Errors())
+	if r, ok := rep.(*erremitter.Renderer); ok {
+		r.Summary(out, &c.ErrEmitter)
+	}
+	if out != os.Stderr {
+		if ferr := out.Close(); ferr != nil {
+			fmt.Fprintf(os.Stderr, "rc: %v\n", ferr)
+			return exitCompileErr
+		}
+	}
+	if err == nil {
+		return exitOk
+	}
+	if !errors.Is(err, driver.ErrCompilationFailed) {
+		fmt.Fprintf(os.Stderr, "rc: %v\n", err)
+	}
+
+	return exitCompileErr
+}
+
+// withOutput runs emit against the file at path, or stdout for "-".
+func withOutput(path string, emit func(w io.Writer) error) error {
+	if path == "-" {
+		return emit(os.Stdout)
+	}
+
+	f, err := os.Create(path)
 	if err != nil {
-		log.Fatal("failed to tokenize source")
+		return err
+	}
+	if err := emit(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+
+	return f.Close()
+}
+
+func runBuild(args []string) int {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	out := fs.String("o", "", "output executable `path` (default: source name without extension)")
+	cc := fs.String("cc", defaultCC(), "C compiler used to build the emitted code")
+	release := fs.Bool("release", false, "build optimized, with integer overflow wrapping around instead of aborting")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageErr
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 	}
-	em := erremitter.NewErrEmitter()
-	p := parser.NewParser(tokens, &em, []rune(src))
 
-	ast := p.Parse()
-	var sb strings.Builder
-	// ast.Function.Print(src, &sb, 0)
+	c, ok := load(path)
+	if !ok {
+		return exitCompileErr
+	}
+	c.Release = *release
+
+	return report(c, c.Build(*cc, *out))
+}
+
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageErr
+	}
+
+	c, ok := load(path)
+	if !ok {
+		return exitCompileErr
+	}
 
-	// fmt.Println(sb.String())
-	// fmt.Println(em.Errors())
-	cg := codegen.NewCodeGenerator(&sb, src)
-	cg.EmitProgram(*ast)
+	return report(c, c.Check())
+}
+
+func runTokens(args []string) int {
+	fs := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	out := fs.String("o", "-", "output `path`, - for stdout")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageErr
+	}
+
+	c, ok := load(path)
+	if !ok {
+		return exitCompileErr
+	}
+
+	return report(c, withOutput(*out, func(w io.Writer) error {
+		// Lexical errors leave Illegal tokens in the stream, which are
+		// printed along with the rest.
+		err := c.Tokenize()
+		for _, tok := range c.Tokens {
+			fmt.Fprintf(w, "%d:%d-%d\t%v\t%q\n", tok.Scope.Line, tok.Scope.Start, tok.Scope.End, tok.Type, tokenText(c.File.Src, tok.Scope.Start, tok.Scope.End))
+		}
+		return err
+	}))
+}
+
+func runAst(args []string) int {
+	fs := flag.NewFlagSet("ast", flag.ContinueOnError)
+	out := fs.String("o", "-", "output `path`, - for stdout")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageErr
+	}
+
+	c, ok := load(path)
+	if !ok {
+		return exitCompileErr
+	}
+
+	return report(c, withOutput(*out, func(w io.Writer) error {
+		if err := c.Parse(); err != nil {
+			return err
+		}
+		var sb strings.Builder
+		c.Program.Print(c.File.Src, &sb, 0)
+		_, err := io.WriteString(w, sb.String())
+		return err
+	}))
+}
+
+func runEmitC(args []string) int {
+	fs := flag.NewFlagSet("emit-c", flag.ContinueOnError)
+	out := fs.String("o", "-", "output `path`, - for stdout")
+	release := fs.Bool("release", false, "emit integer arithmetic that wraps around on overflow instead of aborting")
+	path, ok := parseArgs(fs, args)
+	if !ok {
+		return exitUsageErr
+	}
+
+	c, ok := load(path)
+	if !ok {
+		return exitCompileErr
+	}
+	c.Release = *release
+
+	return report(c, withOutput(*out, c.EmitC))
+}
+
+// reporter returns the diagnostics reporter selected by -error-format for
+// diagnostics written to out. The SARIF log is written even without errors,
+// so it's always a valid file. Like the summary of the text output, the
+// JSON and SARIF output say when the error limit dropped errors.
+func reporter(c *driver.Compilation, out *os.File) erremitter.Reporter {
+	switch errorFormat {
+	case "json":
+		r := erremitter.NewJSONReporter(c.Files)
+		if c.ErrEmitter.LimitReached() {
+			r.SetLimitReached(c.ErrEmitter.MaxErrors())
+		}
+		return r
+	case "sarif":
+		r := erremitter.NewSARIFReporter(c.Files)
+		if c.ErrEmitter.LimitReached() {
+			r.SetLimitReached(c.ErrEmitter.MaxErrors())
+		}
+		return r
+	}
+
+	return erremitter.NewRenderer(c.Files, useColor(out))
+}
+
+// useColor reports whether diagnostics written to f should be colored. In
+// auto mode they are colored when f is a terminal.
+func useColor(f *os.File) bool {
+	switch colorMode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func runExplain(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: rc explain <code>")
+		return exitUsageErr
+	}
+
+	text, ok := erremitter.Explain(erremitter.Code(args[0]))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "rc: unknown error code %q\n", args[0])
+		return exitUsageErr
+	}
+	fmt.Print(text)
+
+	return exitOk
+}
+
+func defaultCC() string {
+	if cc := os.Getenv("CC"); cc != "" {
+		return cc
+	}
+
+	return "cc"
+}
+
+func tokenText(src []rune, start, end int) string {
+	if start >= len(src) {
+		return ""
+	}
 
-	fmt.Println(sb.String())
+	return string(src[start : end+1])
 }
@@ -1,58 +1,411 @@
 package codegen
 
 import (
+	"fmt"
 	"io"
+	"math/big"
 	"strings"
 
+	"github.com/Mixturka/rc/internal/lexer/token"
 	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/source"
+	"github.com/Mixturka/rc/internal/types"
 )
 
+// cTypes maps the built-in rc types to the <stdint.h> types of the same
+// width and signedness.
+var cTypes = map[types.Type]string{
+	types.I8:    "int8_t",
+	types.I16:   "int16_t",
+	types.I32:   "int32_t",
+	types.I64:   "int64_t",
+	types.Isize: "intptr_t",
+	types.U8:    "uint8_t",
+	types.U16:   "uint16_t",
+	types.U32:   "uint32_t",
+	types.U64:   "uint64_t",
+	types.Usize: "uintptr_t",
+	types.Bool:  "bool",
+}
+
+// userPrefix starts the C name of every rc function but main and of every
+// variable, so that none of them can be a C keyword or clash with anything
+// the included headers declare, such as 'int8_t' or 'INT64_MIN'.
+const userPrefix = "rc_u_"
+
 type CodeGenerator struct {
 	w     io.Writer
 	ident int
 	sb    strings.Builder
-	src   string
+	file  *source.File
+	rt    runtime
+
+	// funcNames holds the C names of all functions.
+	funcNames map[string]struct{}
+	// localNames holds the C name of every variable declared in the current
+	// function, keyed by the offset of the declared name in the source.
+	localNames map[int]string
+	usedNames  map[string]struct{}
+	// loopLabels holds the C label prefix of every labeled loop in the
+	// current function, keyed by the loop's offset in the source. Labeled
+	// jumps become gotos to '<prefix>_break' and '<prefix>_continue', which
+	// are only emitted when a jump uses them.
+	loopLabels     map[int]string
+	usedLabels     map[string]struct{}
+	breakLabels    map[int]struct{}
+	continueLabels map[int]struct{}
 }
 
-func NewCodeGenerator(w io.Writer, src string) CodeGenerator {
-	return CodeGenerator{w: w, ident: 0, src: src}
+func NewCodeGenerator(w io.Writer, file *source.File, overflow Overflow) CodeGenerator {
+	return CodeGenerator{
+		w:         w,
+		ident:     0,
+		file:      file,
+		rt:        newRuntime(overflow),
+		funcNames: make(map[string]struct{}),
+	}
 }
 
 func (cg *CodeGenerator) EmitProgram(program ast.Program) {
-	program.Function.Accept(cg)
-	cg.w.Write([]byte(cg.sb.String()))
+	for _, fn := range program.Functions {
+		cg.funcNames[cg.funcName(fn.Name)] = struct{}{}
+	}
+
+	// Prototypes first, so functions can call each other regardless of the
+	// order they are defined in.
+	for _, fn := range program.Functions {
+		cg.beginFunc()
+		cg.emitFuncSignature(*fn)
+		cg.sb.WriteString(";\n")
+	}
+
+	for _, fn := range program.Functions {
+		cg.sb.WriteRune('\n')
+		fn.Accept(cg)
+	}
+
+	// The helpers are only known once the functions are written, but go in
+	// front of them.
+	cg.w.Write([]byte(cg.rt.prelude() + cg.sb.String()))
 }
 
 func (cg *CodeGenerator) EmitFunc(fn ast.Func) {
+	cg.beginFunc()
 	cg.writeIndent()
-	cg.sb.WriteString("int ")
-	cg.sb.WriteString(cg.src[fn.Name.Scope.Start : fn.Name.Scope.End+1])
-	cg.sb.WriteString("() {\n")
+	cg.emitFuncSignature(fn)
+	cg.sb.WriteRune(' ')
+	cg.emitBlock(*fn.Body)
+	cg.sb.WriteRune('\n')
+}
 
-	cg.ident++
-	cg.writeIndent()
+func (cg *CodeGenerator) emitFuncSignature(fn ast.Func) {
+	fn.ReturnType.Accept(cg)
+	cg.sb.WriteRune(' ')
+	cg.sb.WriteString(cg.funcName(fn.Name))
+	cg.sb.WriteRune('(')
+	if len(fn.Params) == 0 {
+		cg.sb.WriteString("void")
+	}
+	for i, param := range fn.Params {
+		if i > 0 {
+			cg.sb.WriteString(", ")
+		}
+		param.Accept(cg)
+	}
+	cg.sb.WriteRune(')')
+}
 
-	fn.Body.Accept(cg)
-	cg.ident--
-	cg.writeIndent()
-	cg.sb.WriteString("}\n")
+func (cg *CodeGenerator) EmitParam(param ast.Param) {
+	param.Type.Accept(cg)
+	cg.sb.WriteRune(' ')
+	cg.sb.WriteString(cg.declareLocal(param.Name))
+}
+
+func (cg *CodeGenerator) EmitNamedType(typ ast.NamedType) {
+	cg.sb.WriteString(cTypes[typ.Type])
 }
 
 func (cg *CodeGenerator) EmitUnaryExpr(expr ast.UnaryExpr) {
-	cg.sb.WriteRune('(')
-	cg.sb.WriteString(cg.src[expr.Op.Scope.Start : expr.Op.Scope.End+1])
-	expr.Rhs.Accept(cg)
-	cg.sb.WriteRune(')')
+	// A negated literal is known to fit its type. 9223372036854775808
+	// doesn't fit in any signed C type though, so the smallest 64-bit value
+	// can't be written as one.
+	if lit, ok := expr.Rhs.(*ast.ConstExpr); ok && expr.Op.Type == token.Minus {
+		if lit.Type.IsSigned() && lit.Type.Bits() == 64 && cg.literal(*lit).Cmp(new(big.Int).Neg(lit.Type.Min())) == 0 {
+			cg.sb.WriteString("INT64_MIN")
+			return
+		}
+		cg.sb.WriteString("(-")
+		lit.Accept(cg)
+		cg.sb.WriteRune(')')
+		return
+	}
+	if expr.Op.Type == token.Minus && expr.Type.IsInteger() {
+		cg.emitArith(expr.Op, negHelper, "attempt to negate with overflow", expr.Type, expr.Rhs)
+		return
+	}
+
+	cg.convertPromoted(expr.Type, func() {
+		cg.sb.WriteRune('(')
+		cg.sb.WriteString(cg.text(expr.Op))
+		expr.Rhs.Accept(cg)
+		cg.sb.WriteRune(')')
+	})
 }
 
 func (cg *CodeGenerator) EmitBinaryExpr(expr ast.BinaryExpr) {
+	if op, ok := checkedOps[expr.Op.Type]; ok && expr.Type.IsInteger() {
+		cg.emitArith(expr.Op, op.name, op.message, expr.Type, expr.Lhs, expr.Rhs)
+		return
+	}
+
+	cg.convertPromoted(expr.Type, func() {
+		cg.sb.WriteRune('(')
+		expr.Lhs.Accept(cg)
+		cg.sb.WriteRune(' ')
+		cg.sb.WriteString(cg.text(expr.Op))
+		cg.sb.WriteRune(' ')
+		expr.Rhs.Accept(cg)
+		cg.sb.WriteRune(')')
+	})
+}
+
+// emitArith writes a call to the runtime helper computing an operator that
+// can overflow, or divide by zero, on operands of type typ. Helpers that can
+// abort are also passed where the operator is, to say so.
+func (cg *CodeGenerator) emitArith(op token.Token, helper, message string, typ types.Type, operands ...ast.Expr) {
+	cg.sb.WriteString(cg.rt.arith(helper, message, typ))
 	cg.sb.WriteRune('(')
-	expr.Lhs.Accept(cg)
+	for i, operand := range operands {
+		if i > 0 {
+			cg.sb.WriteString(", ")
+		}
+		operand.Accept(cg)
+	}
+	if cg.rt.locates(helper) {
+		cg.sb.WriteString(", ")
+		cg.sb.WriteString(cg.location(op))
+	}
+	cg.sb.WriteRune(')')
+}
+
+// location returns the position of tok as a C string, in the same
+// 'path:line:column' form as diagnostics.
+func (cg *CodeGenerator) location(tok token.Token) string {
+	pos := cg.file.Position(tok.Scope.Start)
+	return cString(fmt.Sprintf("%s:%d:%d", cg.file.Path, pos.Line, pos.DisplayColumn))
+}
+
+// convertPromoted writes the result of an operator of type typ with emit.
+// C computes with types narrower than int as int, so their results are
+// converted back, or 'a + b' on two u8 could be 256.
+func (cg *CodeGenerator) convertPromoted(typ types.Type, emit func()) {
+	if !typ.IsInteger() || typ.Bits() >= 32 {
+		emit()
+		return
+	}
+
+	fmt.Fprintf(&cg.sb, "((%s)", cTypes[typ])
+	emit()
+	cg.sb.WriteRune(')')
+}
+
+func (cg *CodeGenerator) EmitBlockStmt(stmt ast.BlockStmt) {
+	cg.writeIndent()
+	cg.emitBlock(stmt)
+	cg.sb.WriteRune('\n')
+}
+
+// emitBlock writes a braced block starting at the current position and
+// ending right after the closing brace, so it can follow a function
+// signature or an 'if' and be followed by an 'else'.
+func (cg *CodeGenerator) emitBlock(block ast.BlockStmt) {
+	cg.sb.WriteString("{\n")
+
+	cg.ident++
+	for _, stmt := range block.Stmts {
+		stmt.Accept(cg)
+	}
+	cg.ident--
+
+	cg.writeIndent()
+	cg.sb.WriteRune('}')
+}
+
+func (cg *CodeGenerator) EmitIfStmt(stmt ast.IfStmt) {
+	cg.writeIndent()
+	cg.emitIf(stmt)
+	cg.sb.WriteRune('\n')
+}
+
+func (cg *CodeGenerator) emitIf(stmt ast.IfStmt) {
+	cg.sb.WriteString("if ")
+	cg.emitCond(stmt.Cond)
+	cg.sb.WriteRune(' ')
+	cg.emitBlock(*stmt.Then)
+
+	switch els := stmt.Else.(type) {
+	case *ast.IfStmt:
+		cg.sb.WriteString(" else ")
+		cg.emitIf(*els)
+	case *ast.BlockStmt:
+		cg.sb.WriteString(" else ")
+		cg.emitBlock(*els)
+	}
+}
+
+func (cg *CodeGenerator) EmitLetStmt(stmt ast.LetStmt) {
+	cg.writeIndent()
+	cg.sb.WriteString(cTypes[stmt.VarType])
 	cg.sb.WriteRune(' ')
-	cg.sb.WriteString(cg.src[expr.Op.Scope.Start : expr.Op.Scope.End+1])
+	// The initializer goes first: in rc it can't see the variable being
+	// declared, but in C it could, so the name is only bound afterwards.
+	init := cg.capture(stmt.Value)
+	cg.sb.WriteString(cg.declareLocal(stmt.Name))
+	cg.sb.WriteString(" = ")
+	cg.sb.WriteString(init)
+	cg.sb.WriteString(";\n")
+}
+
+func (cg *CodeGenerator) EmitWhileStmt(stmt ast.WhileStmt) {
+	cg.writeIndent()
+	cg.beginLoop(&stmt)
+	cg.sb.WriteString("while ")
+	cg.emitCond(stmt.Cond)
 	cg.sb.WriteRune(' ')
-	expr.Rhs.Accept(cg)
-	cg.sb.WriteRune(')')
+	cg.emitLoopBody(&stmt, *stmt.Body, nil)
+}
+
+func (cg *CodeGenerator) EmitLoopStmt(stmt ast.LoopStmt) {
+	cg.writeIndent()
+	cg.beginLoop(&stmt)
+	cg.sb.WriteString("for (;;) ")
+	cg.emitLoopBody(&stmt, *stmt.Body, nil)
+}
+
+// EmitForStmt lowers a range loop to a counted C loop. The end of the range
+// is evaluated once, up front. An inclusive range is left by comparing
+// against its end after the body instead of stepping past it, so a range
+// ending at the largest value of its type can't overflow.
+func (cg *CodeGenerator) EmitForStmt(stmt ast.ForStmt) {
+	cg.writeIndent()
+	cg.beginLoop(&stmt)
+
+	cg.sb.WriteString("for (")
+	cg.sb.WriteString(cTypes[stmt.VarType])
+	cg.sb.WriteRune(' ')
+	start := cg.capture(stmt.Start)
+	end := cg.capture(stmt.End)
+	name := cg.declareLocal(stmt.Var)
+	endName := cg.uniqueLocal(name + "_end")
+	fmt.Fprintf(&cg.sb, "%s = %s, %s = %s; ", name, start, endName, end)
+
+	if !stmt.Inclusive() {
+		fmt.Fprintf(&cg.sb, "%s < %s; %s++) ", name, endName, name)
+		cg.emitLoopBody(&stmt, *stmt.Body, nil)
+		return
+	}
+
+	fmt.Fprintf(&cg.sb, "%s <= %s; %s++) ", name, endName, name)
+	cg.emitLoopBody(&stmt, *stmt.Body, func() {
+		cg.writeIndent()
+		fmt.Fprintf(&cg.sb, "if (%s == %s) break;\n", name, endName)
+	})
+}
+
+func (cg *CodeGenerator) EmitBreakStmt(stmt ast.BreakStmt) {
+	cg.writeIndent()
+	if stmt.Label == nil {
+		cg.sb.WriteString("break;\n")
+		return
+	}
+
+	key := stmt.Target.ScopeStart()
+	cg.breakLabels[key] = struct{}{}
+	fmt.Fprintf(&cg.sb, "goto %s_break;\n", cg.loopLabels[key])
+}
+
+func (cg *CodeGenerator) EmitContinueStmt(stmt ast.ContinueStmt) {
+	cg.writeIndent()
+	if stmt.Label == nil && !isInclusiveFor(stmt.Target) {
+		cg.sb.WriteString("continue;\n")
+		return
+	}
+
+	key := stmt.Target.ScopeStart()
+	cg.continueLabels[key] = struct{}{}
+	fmt.Fprintf(&cg.sb, "goto %s_continue;\n", cg.loopLabels[key])
+}
+
+// isInclusiveFor reports whether loop is a range loop over an inclusive
+// range. A 'continue' in such a loop has to jump to the end-of-range check
+// at the bottom of the body, which a C 'continue' would skip.
+func isInclusiveFor(loop ast.Loop) bool {
+	fs, ok := loop.(*ast.ForStmt)
+	return ok && fs.Inclusive()
+}
+
+// beginLoop picks the C label prefix of a loop that may need labels: every
+// labeled loop and every inclusive range loop. Labels in rc are scoped to
+// their loop, but C labels are function wide, so repeated label names get a
+// numeric suffix.
+func (cg *CodeGenerator) beginLoop(loop ast.Loop) {
+	var base string
+	if label := loop.LoopLabel(); label != nil {
+		base = cg.text(*label)
+	} else if isInclusiveFor(loop) {
+		base = cg.text(loop.(*ast.ForStmt).Var) + "_loop"
+	} else {
+		return
+	}
+
+	prefix := base
+	for i := 1; ; i++ {
+		if _, ok := cg.usedLabels[prefix]; !ok {
+			break
+		}
+		prefix = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	cg.usedLabels[prefix] = struct{}{}
+	cg.loopLabels[loop.ScopeStart()] = prefix
+}
+
+// emitLoopBody writes the body of a loop followed by the labels that
+// 'continue' and 'break' jump to, if any of them did. tail, if not nil,
+// writes statements that run after every iteration, 'continue' included.
+func (cg *CodeGenerator) emitLoopBody(loop ast.Loop, body ast.BlockStmt, tail func()) {
+	key := loop.ScopeStart()
+
+	cg.sb.WriteString("{\n")
+	cg.ident++
+	for _, stmt := range body.Stmts {
+		stmt.Accept(cg)
+	}
+	if _, ok := cg.continueLabels[key]; ok {
+		cg.writeIndent()
+		fmt.Fprintf(&cg.sb, "%s_continue:;\n", cg.loopLabels[key])
+	}
+	if tail != nil {
+		tail()
+	}
+	cg.ident--
+	cg.writeIndent()
+	cg.sb.WriteString("}\n")
+
+	if _, ok := cg.breakLabels[key]; ok {
+		cg.writeIndent()
+		fmt.Fprintf(&cg.sb, "%s_break:;\n", cg.loopLabels[key])
+	}
+}
+
+func (cg *CodeGenerator) EmitExprStmt(stmt ast.ExprStmt) {
+	cg.writeIndent()
+	if assign, ok := stmt.Expr.(*ast.AssignExpr); ok {
+		cg.emitAssign(*assign)
+	} else {
+		stmt.Expr.Accept(cg)
+	}
+	cg.sb.WriteString(";\n")
 }
 
 func (cg *CodeGenerator) EmitReturnStmt(stmt ast.ReturnStmt) {
@@ -62,8 +415,160 @@ func (cg *CodeGenerator) EmitReturnStmt(stmt ast.ReturnStmt) {
 	cg.sb.WriteString(";\n")
 }
 
+func (cg *CodeGenerator) EmitAssignExpr(expr ast.AssignExpr) {
+	cg.sb.WriteRune('(')
+	cg.emitAssign(expr)
+	cg.sb.WriteRune(')')
+}
+
+// emitAssign writes an assignment. Compound assignments that can overflow
+// are spelled out as a plain assignment of the checked operation, which is
+// fine as the target is a variable and evaluating it twice is harmless.
+func (cg *CodeGenerator) emitAssign(expr ast.AssignExpr) {
+	if op, ok := checkedOps[expr.Op.Type]; ok && expr.Type.IsInteger() {
+		expr.Target.Accept(cg)
+		cg.sb.WriteString(" = ")
+		cg.emitArith(expr.Op, op.name, op.message, expr.Type, expr.Target, expr.Value)
+		return
+	}
+
+	expr.Target.Accept(cg)
+	cg.sb.WriteRune(' ')
+	cg.sb.WriteString(cg.text(expr.Op))
+	cg.sb.WriteRune(' ')
+	expr.Value.Accept(cg)
+}
+
+func (cg *CodeGenerator) EmitCallExpr(expr ast.CallExpr) {
+	// Locals never take a function's name in C, so the callee can be
+	// named directly.
+	cg.sb.WriteString(cg.funcName(expr.Func.Name))
+	cg.sb.WriteRune('(')
+	for i, arg := range expr.Args {
+		if i > 0 {
+			cg.sb.WriteString(", ")
+		}
+		arg.Accept(cg)
+	}
+	cg.sb.WriteRune(')')
+}
+
+func (cg *CodeGenerator) EmitVarExpr(expr ast.VarExpr) {
+	cg.sb.WriteString(cg.localNames[expr.Decl.DeclName().Scope.Start])
+}
+
+// emitCond writes a parenthesized condition. Binary expressions already
+// come with parentheses, and doubling them makes clang warn about '=='.
+func (cg *CodeGenerator) emitCond(cond ast.Expr) {
+	if _, ok := cond.(*ast.BinaryExpr); ok {
+		cond.Accept(cg)
+		return
+	}
+
+	cg.sb.WriteRune('(')
+	cond.Accept(cg)
+	cg.sb.WriteRune(')')
+}
+
+// EmitIfExpr lowers an 'if' expression to the conditional operator. The
+// branches are plain expressions, so only the taken one is evaluated, just
+// as in rc.
+func (cg *CodeGenerator) EmitIfExpr(expr ast.IfExpr) {
+	cg.sb.WriteRune('(')
+	expr.Cond.Accept(cg)
+	cg.sb.WriteString(" ? ")
+	expr.Then.Accept(cg)
+	cg.sb.WriteString(" : ")
+	expr.Else.Accept(cg)
+	cg.sb.WriteRune(')')
+}
+
+// EmitConstExpr writes a literal in decimal without leading zeros, which C
+// would read as octal. Unsigned literals too large for int64_t get a suffix
+// so the C compiler doesn't warn about them.
 func (cg *CodeGenerator) EmitConstExpr(expr ast.ConstExpr) {
-	cg.sb.WriteString(cg.src[expr.Value.Scope.Start : expr.Value.Scope.End+1])
+	v := cg.literal(expr)
+	if !expr.Type.IsSigned() && !types.I64.Fits(v) {
+		fmt.Fprintf(&cg.sb, "UINT64_C(%s)", v)
+		return
+	}
+	cg.sb.WriteString(v.String())
+}
+
+func (cg *CodeGenerator) EmitBoolExpr(expr ast.BoolExpr) {
+	cg.sb.WriteString(cg.text(expr.Value))
+}
+
+// EmitCastExpr converts with a C cast, which truncates or extends the value
+// to the target type.
+func (cg *CodeGenerator) EmitCastExpr(expr ast.CastExpr) {
+	cg.sb.WriteString("((")
+	expr.Target.Accept(cg)
+	cg.sb.WriteString(")")
+	expr.Expr.Accept(cg)
+	cg.sb.WriteRune(')')
+}
+
+func (cg *CodeGenerator) literal(expr ast.ConstExpr) *big.Int {
+	v, _ := new(big.Int).SetString(cg.text(expr.Value), 10)
+	return v
+}
+
+func (cg *CodeGenerator) beginFunc() {
+	cg.localNames = make(map[int]string)
+	cg.usedNames = make(map[string]struct{})
+	cg.loopLabels = make(map[int]string)
+	cg.usedLabels = make(map[string]struct{})
+	cg.breakLabels = make(map[int]struct{})
+	cg.continueLabels = make(map[int]struct{})
+}
+
+// funcName returns the C name of the function declared by name. It is the
+// rc name with userPrefix in front, except for main, which C has to find.
+func (cg *CodeGenerator) funcName(name token.Token) string {
+	if cg.text(name) == "main" {
+		return "main"
+	}
+	return userPrefix + cg.text(name)
+}
+
+// declareLocal picks the C name for a variable declared by name. rc lets
+// variables shadow each other and functions, C doesn't always, so every
+// declaration in a function gets a distinct name.
+func (cg *CodeGenerator) declareLocal(name token.Token) string {
+	cName := cg.uniqueLocal(userPrefix + cg.text(name))
+	cg.localNames[name.Scope.Start] = cName
+	return cName
+}
+
+// uniqueLocal reserves a C name based on base that is not taken by any
+// function or variable of the current function.
+func (cg *CodeGenerator) uniqueLocal(base string) string {
+	cName := base
+	for i := 1; ; i++ {
+		_, isFunc := cg.funcNames[cName]
+		_, isUsed := cg.usedNames[cName]
+		if !isFunc && !isUsed {
+			break
+		}
+		cName = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	cg.usedNames[cName] = struct{}{}
+	return cName
+}
+
+// capture emits expr into a separate buffer and returns the C code.
+func (cg *CodeGenerator) capture(expr ast.Expr) string {
+	var out strings.Builder
+	cg.sb, out = out, cg.sb
+	expr.Accept(cg)
+	cg.sb, out = out, cg.sb
+	return out.String()
+}
+
+func (cg *CodeGenerator) text(tok token.Token) string {
+	return string(cg.file.Src[tok.Scope.Start : tok.Scope.End+1])
 }
 
 func (cg *CodeGenerator) writeIndent() {
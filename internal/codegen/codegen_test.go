@@ -0,0 +1,284 @@
+package codegen_test
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/driver"
+)
+
+// emitC translates src, read from test.rc, to C. release selects wrapping
+// arithmetic instead of trapping on overflow.
+func emitC(t *testing.T, src string, release bool) string {
+	t.Helper()
+
+	c := driver.NewCompilation("test.rc", []rune(src))
+	c.Release = release
+	var sb strings.Builder
+	if err := c.EmitC(&sb); err != nil {
+		var messages []string
+		for _, e := range c.Errors() {
+			messages = append(messages, e.Message)
+		}
+		t.Fatalf("Expected no errors, got %v", messages)
+	}
+	return sb.String()
+}
+
+// expectContains fails unless every line of expected is in the C code.
+func expectContains(t *testing.T, code string, expected ...string) {
+	t.Helper()
+
+	for _, line := range expected {
+		if !strings.Contains(code, line) {
+			t.Errorf("Expected %q in:\n%s", line, code)
+		}
+	}
+}
+
+func TestEmitNonASCIISource(t *testing.T) {
+	code := emitC(t, "// café ☕\nfn main() -> i32 { let x: i32 = 1; return x + 2; }", false)
+	expectContains(t, code,
+		"int32_t main(void) {",
+		"int32_t rc_u_x = 1;",
+		`return rc_add_i32(rc_u_x, 2, "test.rc:2:45");`,
+	)
+}
+
+func TestEmitPrototypes(t *testing.T) {
+	code := emitC(t, `fn main() -> i32 { return twice(zero()); }
+	fn twice(n: i32) -> i32 { return n * 2; }
+	fn zero() -> i32 { return 0; }`, true)
+	expectContains(t, code,
+		"int32_t main(void);\nint32_t rc_u_twice(int32_t rc_u_n);\nint32_t rc_u_zero(void);\n",
+		"int32_t main(void) {\n  return rc_u_twice(rc_u_zero());\n}\n",
+	)
+	if prototype, definition := strings.Index(code, "rc_u_twice(int32_t rc_u_n);"), strings.Index(code, "int32_t main(void) {"); prototype > definition {
+		t.Errorf("Expected the prototypes before the functions, got:\n%s", code)
+	}
+}
+
+func TestEmitPrefixedNames(t *testing.T) {
+	code := emitC(t, `fn setenv(int32_t: i32) -> i32 { return int32_t; }
+	fn main() -> i32 {
+		let INT32_MIN: i32 = 1; let setenv = setenv(2); let main = 3;
+		for i in 0..main { setenv += i; }
+		return INT32_MIN + setenv;
+	}`, false)
+	expectContains(t, code,
+		"int32_t rc_u_setenv(int32_t rc_u_int32_t);",
+		"int32_t main(void);",
+		"int32_t rc_u_INT32_MIN = 1;",
+		"int32_t rc_u_setenv_1 = rc_u_setenv(2);",
+		"int32_t rc_u_main = 3;",
+		"for (int32_t rc_u_i = 0, rc_u_i_end = rc_u_main; rc_u_i < rc_u_i_end; rc_u_i++) {",
+		`return rc_add_i32(rc_u_INT32_MIN, rc_u_setenv_1, "test.rc:5:34");`,
+	)
+}
+
+// arithSrc uses every operator that can overflow, on a narrow type for the
+// shifts.
+const arithSrc = `fn f(a: i32, b: i32, c: i8, n: u32) -> i32 {
+	let x = a + b - a * b;
+	let y = c << 3 >> n;
+	x += -a;
+	return x + y as i32;
+}
+fn main() -> i32 { return f(1, 2, 3, 1); }`
+
+func TestEmitTrappingArithmetic(t *testing.T) {
+	code := emitC(t, arithSrc, false)
+	expectContains(t, code,
+		"#include <stdio.h>\n#include <stdlib.h>\n",
+		"static void rc_panic(const char *message, const char *at) {\n"+
+			"  fprintf(stderr, \"%s at %s\\n\", message, at);\n"+
+			"  abort();\n"+
+			"}\n",
+		"static inline int32_t rc_add_i32(int32_t a, int32_t b, const char *at) {\n"+
+			"  int32_t r;\n"+
+			"  if (__builtin_add_overflow(a, b, &r)) rc_panic(\"attempt to add with overflow\", at);\n"+
+			"  return r;\n"+
+			"}\n",
+		"if (__builtin_sub_overflow(a, b, &r)) rc_panic(\"attempt to subtract with overflow\", at);",
+		"if (__builtin_mul_overflow(a, b, &r)) rc_panic(\"attempt to multiply with overflow\", at);",
+		"static inline int32_t rc_neg_i32(int32_t a, const char *at) {\n"+
+			"  int32_t r;\n"+
+			"  if (__builtin_sub_overflow(0, a, &r)) rc_panic(\"attempt to negate with overflow\", at);\n"+
+			"  return r;\n"+
+			"}\n",
+		"static inline int8_t rc_shl_i8(int8_t a, uint64_t n, const char *at) {\n"+
+			"  if (n >= 8) rc_panic(\"attempt to shift left with overflow\", at);\n"+
+			"  return (int8_t)((uint8_t)a << n);\n"+
+			"}\n",
+		"static inline int8_t rc_shr_i8(int8_t a, uint64_t n, const char *at) {\n"+
+			"  if (n >= 8) rc_panic(\"attempt to shift right with overflow\", at);\n"+
+			"  return a >> n;\n"+
+			"}\n",
+		`int32_t rc_u_x = rc_sub_i32(rc_add_i32(rc_u_a, rc_u_b, "test.rc:2:19"), rc_mul_i32(rc_u_a, rc_u_b, "test.rc:2:27"), "test.rc:2:23");`,
+		`int8_t rc_u_y = rc_shr_i8(rc_shl_i8(rc_u_c, 3, "test.rc:3:19"), rc_u_n, "test.rc:3:24");`,
+		`rc_u_x = rc_add_i32(rc_u_x, rc_neg_i32(rc_u_a, "test.rc:4:14"), "test.rc:4:11");`,
+	)
+}
+
+func TestEmitWrappingArithmetic(t *testing.T) {
+	code := emitC(t, arithSrc, true)
+	expectContains(t, code,
+		"static inline int32_t rc_add_i32(int32_t a, int32_t b) {\n"+
+			"  int32_t r;\n"+
+			"  __builtin_add_overflow(a, b, &r);\n"+
+			"  return r;\n"+
+			"}\n",
+		"  __builtin_sub_overflow(a, b, &r);\n",
+		"  __builtin_mul_overflow(a, b, &r);\n",
+		"static inline int32_t rc_neg_i32(int32_t a) {\n"+
+			"  int32_t r;\n"+
+			"  __builtin_sub_overflow(0, a, &r);\n"+
+			"  return r;\n"+
+			"}\n",
+		"static inline int8_t rc_shl_i8(int8_t a, uint64_t n) {\n"+
+			"  n &= 7;\n"+
+			"  return (int8_t)((uint8_t)a << n);\n"+
+			"}\n",
+		"static inline int8_t rc_shr_i8(int8_t a, uint64_t n) {\n"+
+			"  n &= 7;\n"+
+			"  return a >> n;\n"+
+			"}\n",
+		"int32_t rc_u_x = rc_sub_i32(rc_add_i32(rc_u_a, rc_u_b), rc_mul_i32(rc_u_a, rc_u_b));",
+		"int8_t rc_u_y = rc_shr_i8(rc_shl_i8(rc_u_c, 3), rc_u_n);",
+		"rc_u_x = rc_add_i32(rc_u_x, rc_neg_i32(rc_u_a));",
+	)
+	if strings.Contains(code, "rc_panic") {
+		t.Errorf("Expected no rc_panic when wrapping, got:\n%s", code)
+	}
+}
+
+func TestEmitNarrowResultsAreConverted(t *testing.T) {
+	code := emitC(t, "fn main() -> i32 { let d: u8 = 200; let e = ~d & d | 1; return e as i32; }", false)
+	expectContains(t, code, "uint8_t rc_u_e = ((uint8_t)(((uint8_t)(((uint8_t)(~rc_u_d)) & rc_u_d)) | 1));")
+}
+
+// run builds src with the C compiler and runs it, returning what it wrote
+// to stderr and its exit code. It skips the test without a C compiler.
+func run(t *testing.T, src string, release bool) (string, int) {
+	t.Helper()
+
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler found")
+	}
+
+	c := driver.NewCompilation("test.rc", []rune(src))
+	c.Release = release
+	exe := filepath.Join(t.TempDir(), "test")
+	if err := c.Build(cc, exe); err != nil {
+		t.Fatalf("Expected the program to build, got %v", err)
+	}
+
+	var stderr strings.Builder
+	cmd := exec.Command(exe)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("Expected the program to run, got %v", err)
+	}
+	return stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+const overflowSrc = `fn add(a: i32, b: i32) -> i32 { return a + b; }
+fn main() -> i32 {
+	let x = add(2147483647, 1);
+	return if x == -2147483648 { 7 } else { 1 };
+}`
+
+func TestRunOverflowTraps(t *testing.T) {
+	stderr, code := run(t, overflowSrc, false)
+	expected := "attempt to add with overflow at test.rc:1:42\n"
+	if stderr != expected || code == 0 {
+		t.Errorf("Expected: %q and a failure, got %q and exit code %d", expected, stderr, code)
+	}
+}
+
+func TestRunOverflowWraps(t *testing.T) {
+	stderr, code := run(t, overflowSrc, true)
+	if stderr != "" || code != 7 {
+		t.Errorf("Expected exit code 7, got %d (stderr: %q)", code, stderr)
+	}
+}
+
+const divSrc = `fn f(a: i64, b: i64, c: u16, d: u16) -> i64 {
+	let q = a / b;
+	c /= d;
+	return q + a % b + (c % d) as i64;
+}
+fn main() -> i32 { return f(1, 2, 3, 4) as i32; }`
+
+func TestEmitCheckedDivision(t *testing.T) {
+	code := emitC(t, divSrc, false)
+	expectContains(t, code,
+		"static inline int64_t rc_div_i64(int64_t a, int64_t b, const char *at) {\n"+
+			"  if (b == 0) rc_panic(\"attempt to divide by zero\", at);\n"+
+			"  if (b == -1 && a == INT64_MIN) rc_panic(\"attempt to divide with overflow\", at);\n"+
+			"  return a / b;\n"+
+			"}\n",
+		"static inline int64_t rc_rem_i64(int64_t a, int64_t b, const char *at) {\n"+
+			"  if (b == 0) rc_panic(\"attempt to divide by zero\", at);\n"+
+			"  if (b == -1 && a == INT64_MIN) return 0;\n"+
+			"  return a % b;\n"+
+			"}\n",
+		"static inline uint16_t rc_div_u16(uint16_t a, uint16_t b, const char *at) {\n"+
+			"  if (b == 0) rc_panic(\"attempt to divide by zero\", at);\n"+
+			"  return a / b;\n"+
+			"}\n",
+		`int64_t rc_u_q = rc_div_i64(rc_u_a, rc_u_b, "test.rc:2:19");`,
+		`rc_u_c = rc_div_u16(rc_u_c, rc_u_d, "test.rc:3:11");`,
+		`rc_rem_i64(rc_u_a, rc_u_b, "test.rc:4:22")`,
+		`((int64_t)rc_rem_u16(rc_u_c, rc_u_d, "test.rc:4:31"))`,
+	)
+}
+
+func TestEmitCheckedDivisionWhenWrapping(t *testing.T) {
+	code := emitC(t, divSrc, true)
+	expectContains(t, code,
+		"static void rc_panic(const char *message, const char *at) {",
+		"static inline int64_t rc_div_i64(int64_t a, int64_t b, const char *at) {\n"+
+			"  if (b == 0) rc_panic(\"attempt to divide by zero\", at);\n"+
+			"  if (b == -1 && a == INT64_MIN) return a;\n"+
+			"  return a / b;\n"+
+			"}\n",
+		"  if (b == -1 && a == INT64_MIN) return 0;\n  return a % b;\n",
+		`int64_t rc_u_q = rc_div_i64(rc_u_a, rc_u_b, "test.rc:2:19");`,
+	)
+}
+
+const divideSrc = `fn div(a: i32, b: i32) -> i32 { return a / b; }
+fn rem(a: i32, b: i32) -> i32 { return a % b; }
+fn main() -> i32 {
+	let min = -2147483648;
+	if rem(min, -1) != 0 || rem(7, -2) != 1 {
+		return 1;
+	}
+	if div(min, -1) != min {
+		return 2;
+	}
+	return div(1, 0);
+}`
+
+func TestRunDivisionTraps(t *testing.T) {
+	stderr, code := run(t, divideSrc, false)
+	expected := "attempt to divide with overflow at test.rc:1:42\n"
+	if stderr != expected || code == 0 {
+		t.Errorf("Expected: %q and a failure, got %q and exit code %d", expected, stderr, code)
+	}
+}
+
+func TestRunDivisionByZeroTrapsWhenWrapping(t *testing.T) {
+	stderr, code := run(t, divideSrc, true)
+	expected := "attempt to divide by zero at test.rc:1:42\n"
+	if stderr != expected || code == 0 {
+		t.Errorf("Expected: %q and a failure, got %q and exit code %d", expected, stderr, code)
+	}
+}
@@ -0,0 +1,232 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/types"
+)
+
+// Overflow selects what integer arithmetic does when its result doesn't fit
+// its type. Either way the result is defined, unlike signed overflow in C,
+// which the C compiler may assume never happens.
+type Overflow int
+
+const (
+	// OverflowTrap aborts the program with a message saying which
+	// operation overflowed and where. It is meant for debug builds.
+	OverflowTrap Overflow = iota
+	// OverflowWrap wraps the result around, keeping its low bits, as
+	// unsigned arithmetic in C does.
+	OverflowWrap
+)
+
+// checkedOps maps the operators that can overflow or divide by zero to the
+// name of their helper and the message it traps with on overflow. Compound
+// assignments use the helper of their operator.
+var checkedOps = map[token.TokenType]struct {
+	name, message string
+}{
+	token.Plus:                 {"add", "attempt to add with overflow"},
+	token.PlusAssign:           {"add", "attempt to add with overflow"},
+	token.Minus:                {"sub", "attempt to subtract with overflow"},
+	token.MinusAssign:          {"sub", "attempt to subtract with overflow"},
+	token.Star:                 {"mul", "attempt to multiply with overflow"},
+	token.StarAssign:           {"mul", "attempt to multiply with overflow"},
+	token.LessLess:             {"shl", "attempt to shift left with overflow"},
+	token.LessLessAssign:       {"shl", "attempt to shift left with overflow"},
+	token.GreaterGreater:       {"shr", "attempt to shift right with overflow"},
+	token.GreaterGreaterAssign: {"shr", "attempt to shift right with overflow"},
+	token.Slash:                {"div", "attempt to divide with overflow"},
+	token.SlashAssign:          {"div", "attempt to divide with overflow"},
+	token.Percent:              {"rem", "attempt to calculate the remainder with overflow"},
+}
+
+// negHelper is the helper of unary minus, which only overflows for the
+// smallest value of a signed type.
+const negHelper = "neg"
+
+// runtime collects the C helpers a program uses, so that only those are
+// emitted in front of it.
+type runtime struct {
+	overflow Overflow
+	// helpers holds the definitions of the helpers used so far, in the
+	// order they were first used, and names the same helpers by name.
+	helpers []string
+	names   map[string]struct{}
+	panics  bool
+}
+
+func newRuntime(overflow Overflow) runtime {
+	return runtime{overflow: overflow, names: make(map[string]struct{})}
+}
+
+// locates reports whether the helper of op takes the location of the
+// operation as its last argument, to say where the program aborted. Division
+// and remainder abort on a zero divisor even when overflow wraps.
+func (rt *runtime) locates(op string) bool {
+	return rt.overflow == OverflowTrap || op == "div" || op == "rem"
+}
+
+// arith returns the name of the helper computing op, one of checkedOps or
+// negHelper, on values of type typ, and defines the helper if it is the
+// first use.
+func (rt *runtime) arith(op string, message string, typ types.Type) string {
+	name := fmt.Sprintf("rc_%s_%s", op, typ)
+	if _, ok := rt.names[name]; ok {
+		return name
+	}
+	rt.names[name] = struct{}{}
+
+	cType := cTypes[typ]
+	trap := rt.overflow == OverflowTrap
+	rt.panics = rt.panics || rt.locates(op)
+
+	var sb strings.Builder
+	params := fmt.Sprintf("%s a, %s b", cType, cType)
+	switch op {
+	case negHelper:
+		params = cType + " a"
+	case "shl", "shr":
+		// The amount can have any integer type. Negative amounts turn into
+		// huge ones, which are out of range as well.
+		params = cType + " a, uint64_t n"
+	}
+	if rt.locates(op) {
+		params += ", const char *at"
+	}
+	fmt.Fprintf(&sb, "static inline %s %s(%s) {\n", cType, name, params)
+
+	switch op {
+	case "shl", "shr":
+		// C leaves shifts by the width of the type or more undefined, so
+		// they trap or, like the shift instructions of most CPUs, only use
+		// the low bits of the amount.
+		if trap {
+			fmt.Fprintf(&sb, "  if (n >= %d) rc_panic(%s, at);\n", typ.Bits(), cString(message))
+		} else {
+			fmt.Fprintf(&sb, "  n &= %d;\n", typ.Bits()-1)
+		}
+		if op == "shl" {
+			// Shifting a negative value left is undefined as well, so the
+			// bits are shifted as unsigned.
+			fmt.Fprintf(&sb, "  return (%s)((%s)a << n);\n", cType, cTypes[unsigned(typ)])
+		} else {
+			sb.WriteString("  return a >> n;\n")
+		}
+	case "div", "rem":
+		sb.WriteString("  if (b == 0) rc_panic(\"attempt to divide by zero\", at);\n")
+		if typ.IsSigned() {
+			// The smallest value divided by -1 is one past the largest, which
+			// C leaves undefined, for the remainder as well on most CPUs.
+			// Mathematically the remainder is 0 though, and wrapping the
+			// quotient gives the smallest value back.
+			fmt.Fprintf(&sb, "  if (b == -1 && a == %s) ", minName(typ))
+			switch {
+			case op == "rem":
+				sb.WriteString("return 0;\n")
+			case trap:
+				fmt.Fprintf(&sb, "rc_panic(%s, at);\n", cString(message))
+			default:
+				sb.WriteString("return a;\n")
+			}
+		}
+		if op == "div" {
+			sb.WriteString("  return a / b;\n")
+		} else {
+			sb.WriteString("  return a % b;\n")
+		}
+	default:
+		builtin, operands := op, "a, b"
+		if op == negHelper {
+			builtin, operands = "sub", "0, a"
+		}
+		fmt.Fprintf(&sb, "  %s r;\n", cType)
+		if trap {
+			fmt.Fprintf(&sb, "  if (__builtin_%s_overflow(%s, &r)) rc_panic(%s, at);\n", builtin, operands, cString(message))
+		} else {
+			// The builtins store the wrapped result even when it overflows.
+			fmt.Fprintf(&sb, "  __builtin_%s_overflow(%s, &r);\n", builtin, operands)
+		}
+		sb.WriteString("  return r;\n")
+	}
+	sb.WriteString("}\n")
+
+	rt.helpers = append(rt.helpers, sb.String())
+	return name
+}
+
+// prelude returns the includes and helpers the program needs, ready to be
+// written in front of it.
+func (rt *runtime) prelude() string {
+	var sb strings.Builder
+	sb.WriteString("#include <stdbool.h>\n#include <stdint.h>\n")
+	if rt.panics {
+		sb.WriteString("#include <stdio.h>\n#include <stdlib.h>\n")
+	}
+	sb.WriteRune('\n')
+
+	if rt.panics {
+		sb.WriteString("static void rc_panic(const char *message, const char *at) {\n")
+		sb.WriteString("  fprintf(stderr, \"%s at %s\\n\", message, at);\n")
+		sb.WriteString("  abort();\n")
+		sb.WriteString("}\n\n")
+	}
+	for _, helper := range rt.helpers {
+		sb.WriteString(helper)
+		sb.WriteRune('\n')
+	}
+
+	return sb.String()
+}
+
+// unsigned returns the unsigned type as wide as the integer type typ.
+func unsigned(typ types.Type) types.Type {
+	switch typ {
+	case types.I8:
+		return types.U8
+	case types.I16:
+		return types.U16
+	case types.I32:
+		return types.U32
+	case types.I64:
+		return types.U64
+	case types.Isize:
+		return types.Usize
+	}
+
+	return typ
+}
+
+// minName returns the <stdint.h> macro for the smallest value of the signed
+// type typ.
+func minName(typ types.Type) string {
+	if typ == types.Isize {
+		return "INTPTR_MIN"
+	}
+
+	return fmt.Sprintf("INT%d_MIN", typ.Bits())
+}
+
+// cString quotes s as a C string literal. Bytes outside of printable ASCII
+// are written as octal escapes, so the literal means the same whatever the
+// encoding the C compiler assumes.
+func cString(s string) string {
+	var sb strings.Builder
+	sb.WriteRune('"')
+	for _, b := range []byte(s) {
+		switch {
+		case b == '"' || b == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case b < 0x20 || b >= 0x7f:
+			fmt.Fprintf(&sb, "\\%03o", b)
+		default:
+			sb.WriteByte(b)
+		}
+	}
+	sb.WriteRune('"')
+
+	return sb.String()
+}
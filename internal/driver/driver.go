@@ -0,0 +1,187 @@
+package driver
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Mixturka/rc/internal/codegen"
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer"
+	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/parser"
+	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/source"
+	"github.com/Mixturka/rc/internal/sema"
+)
+
+var (
+	ErrCompilationFailed = errors.New("compilation failed")
+)
+
+// Compilation carries a single source file through the compiler pipeline.
+// Every stage runs the stages it depends on, so callers can ask for the
+// furthest result they need.
+type Compilation struct {
+	Files *source.FileSet
+	// File is the file being compiled, one of Files.
+	File       *source.File
+	Tokens     []token.Token
+	Program    *ast.Program
+	ErrEmitter erremitter.ErrEmitter
+	// lexErrs holds the lexical errors until they are reported, which the
+	// parser's are merged with so that both are reported in source order.
+	lexErrs []erremitter.Err
+	// Release selects a release build, where integer arithmetic wraps
+	// around on overflow instead of aborting the program, and the C
+	// compiler optimizes.
+	Release bool
+}
+
+func NewCompilation(path string, src []rune) *Compilation {
+	files := source.NewFileSet()
+	return &Compilation{
+		Files:      files,
+		File:       files.Add(path, src),
+		ErrEmitter: erremitter.NewErrEmitter(),
+	}
+}
+
+func Load(path string) (*Compilation, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCompilation(path, []rune(string(src))), nil
+}
+
+func (c *Compilation) Tokenize() error {
+	c.lex()
+	c.report(c.lexErrs)
+	return c.failIfErrors()
+}
+
+// lex tokenizes the source unless it's already done. Lexical errors don't
+// stop the pipeline, the parser goes on past the Illegal tokens.
+func (c *Compilation) lex() {
+	if c.Tokens != nil {
+		return
+	}
+
+	em := erremitter.NewErrEmitter()
+	em.SetMaxErrors(0)
+	l := lexer.NewFileLexer(c.File, &em)
+	c.Tokens = l.Tokenize()
+	c.lexErrs = em.Errors()
+}
+
+// report adds errs to the compilation's diagnostics in source order, so
+// that the error limit keeps the earliest ones.
+func (c *Compilation) report(errs []erremitter.Err) {
+	slices.SortStableFunc(errs, func(a, b erremitter.Err) int {
+		return cmp.Compare(a.ErrScope.Start, b.ErrScope.Start)
+	})
+	for _, err := range errs {
+		c.ErrEmitter.Add(err)
+	}
+	c.lexErrs = nil
+}
+
+func (c *Compilation) Parse() error {
+	if c.Program != nil {
+		return c.failIfErrors()
+	}
+	c.lex()
+
+	// The parser stops at the error limit on its own errors, which is
+	// still enough to know the earliest errors once the lexer's are in.
+	em := erremitter.NewErrEmitter()
+	em.SetMaxErrors(c.ErrEmitter.MaxErrors())
+	p := parser.NewParser(c.Tokens, &em, c.File.Src)
+	c.Program = p.Parse()
+	c.report(append(c.lexErrs, em.Errors()...))
+
+	return c.failIfErrors()
+}
+
+func (c *Compilation) Check() error {
+	if err := c.Parse(); err != nil {
+		return err
+	}
+
+	r := sema.NewResolver(&c.ErrEmitter, c.File)
+	r.Resolve(c.Program)
+	tc := sema.NewChecker(&c.ErrEmitter, c.File)
+	tc.Check(c.Program)
+
+	return c.failIfErrors()
+}
+
+func (c *Compilation) EmitC(w io.Writer) error {
+	if err := c.Check(); err != nil {
+		return err
+	}
+
+	overflow := codegen.OverflowTrap
+	if c.Release {
+		overflow = codegen.OverflowWrap
+	}
+	cg := codegen.NewCodeGenerator(w, c.File, overflow)
+	cg.EmitProgram(*c.Program)
+
+	return nil
+}
+
+// Build emits C for the compilation and hands it to the C compiler cc,
+// producing an executable at out.
+func (c *Compilation) Build(cc string, out string) error {
+	var sb strings.Builder
+	if err := c.EmitC(&sb); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "rc-build-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	cPath := filepath.Join(dir, strings.TrimSuffix(filepath.Base(c.File.Path), filepath.Ext(c.File.Path))+".c")
+	if err := os.WriteFile(cPath, []byte(sb.String()), 0o644); err != nil {
+		return err
+	}
+
+	args := []string{"-o", out, cPath}
+	if c.Release {
+		args = append([]string{"-O2"}, args...)
+	}
+	cmd := exec.Command(cc, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", cc, err)
+	}
+
+	return nil
+}
+
+func (c *Compilation) Errors() []erremitter.Err {
+	return c.ErrEmitter.Errors()
+}
+
+// failIfErrors fails a stage that reported errors. Warnings alone don't
+// stop the pipeline.
+func (c *Compilation) failIfErrors() error {
+	if c.ErrEmitter.ErrorCount() > 0 {
+		return ErrCompilationFailed
+	}
+
+	return nil
+}
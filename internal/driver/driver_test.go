@@ -0,0 +1,44 @@
+package driver_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/driver"
+)
+
+// parseErrors parses src with an error limit of maxErrors and returns the
+// messages of the errors reported.
+func parseErrors(t *testing.T, src string, maxErrors int) []string {
+	t.Helper()
+
+	c := driver.NewCompilation("test.rc", []rune(src))
+	c.ErrEmitter.SetMaxErrors(maxErrors)
+	if err := c.Parse(); err == nil {
+		t.Fatalf("Expected the parse to fail")
+	}
+
+	var messages []string
+	for _, e := range c.Errors() {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+const lexAfterSyntaxSrc = "fn main() -> i32 { return 1 + ; }\nfn f() -> i32 { return @; }"
+
+func TestParseReportsErrorsInSourceOrder(t *testing.T) {
+	errs := parseErrors(t, lexAfterSyntaxSrc, 0)
+	expected := []string{"expected expression", "unexpected character '@'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestParseErrorLimitKeepsEarliestErrors(t *testing.T) {
+	errs := parseErrors(t, lexAfterSyntaxSrc, 1)
+	expected := []string{"expected expression"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
@@ -0,0 +1,293 @@
+package erremitter
+
+import "strings"
+
+// Code is the stable identifier of a kind of diagnostic. Codes are never
+// reused, so they can be searched for and passed to 'rc explain'. Error
+// codes start with E and warning codes with W.
+type Code string
+
+const (
+	CodeSyntax              Code = "E0001"
+	CodeIncDec              Code = "E0002"
+	CodeUndeclaredVar       Code = "E0003"
+	CodeFuncAsValue         Code = "E0004"
+	CodeUndefinedFunc       Code = "E0005"
+	CodeNotCallable         Code = "E0006"
+	CodeArgCount            Code = "E0007"
+	CodeDuplicateFunc       Code = "E0008"
+	CodeDuplicateParam      Code = "E0009"
+	CodeRedeclaredVar       Code = "E0010"
+	CodeInvalidAssignTarget Code = "E0011"
+	CodeAssignLoopVar       Code = "E0012"
+	CodeJumpOutsideLoop     Code = "E0013"
+	CodeUndeclaredLabel     Code = "E0014"
+	CodeUnexpectedChar      Code = "E0015"
+	CodeUnterminatedComment Code = "E0016"
+	CodeUnknownType         Code = "E0017"
+	CodeLiteralOutOfRange   Code = "E0018"
+	CodeMismatchedTypes     Code = "E0019"
+	CodeInvalidOperand      Code = "E0020"
+	CodeMissingReturn       Code = "E0021"
+	CodeInvalidCast         Code = "E0022"
+	CodeShiftOutOfRange     Code = "E0023"
+	CodeDivisionByZero      Code = "E0024"
+	CodeAssignInExpr        Code = "E0025"
+	CodeInvalidMain         Code = "E0026"
+
+	CodeUnusedVariable Code = "W0001"
+)
+
+// lintNames maps the names warnings are switched on and off by to their
+// codes.
+var lintNames = map[string]Code{
+	"unused-variable": CodeUnusedVariable,
+}
+
+// LintCode returns the code of the warning called name. The code itself,
+// e.g. "W0001", is accepted as a name as well.
+func LintCode(name string) (Code, bool) {
+	if code, ok := lintNames[name]; ok {
+		return code, true
+	}
+	code := Code(strings.ToUpper(name))
+	if _, ok := explanations[code]; ok && strings.HasPrefix(string(code), "W") {
+		return code, true
+	}
+
+	return "", false
+}
+
+var explanations = map[Code]string{
+	CodeSyntax: `
+The source doesn't follow the grammar of rc. The message says what the
+parser expected instead of the token it found.
+
+After a syntax error the parser skips ahead to the next statement or
+function and goes on, so errors that follow the first one may be caused by
+it.
+`,
+	CodeIncDec: `
+rc has no '++' or '--' operators. Use a compound assignment instead:
+
+    x += 1;
+    x -= 1;
+
+To negate a value twice, separate the minus signs: '- -x'.
+`,
+	CodeUndeclaredVar: `
+A variable was used that isn't declared at that point. Variables are
+visible from their 'let' to the end of the enclosing block, and parameters
+in the whole function body:
+
+    fn main() -> i32 {
+        { let x = 1; }
+        return x; // 'x' went out of scope with its block
+    }
+
+The initializer of a 'let' can't refer to the variable it declares.
+`,
+	CodeFuncAsValue: `
+A function name was used as a value. Functions can only be called:
+
+    fn one() -> i32 { return 1; }
+    fn main() -> i32 { return one; } // should be one()
+`,
+	CodeUndefinedFunc: `
+A function was called that isn't defined anywhere in the program. Functions
+can be called before or after their definition, but they must be defined in
+the same file.
+`,
+	CodeNotCallable: `
+Something other than a function name was called, as in '(1 + 2)(3)'. Only
+functions defined with 'fn' can be called.
+`,
+	CodeArgCount: `
+A function was called with a different number of arguments than it has
+parameters:
+
+    fn add(a: i32, b: i32) -> i32 { return a + b; }
+    fn main() -> i32 { return add(1); } // add takes 2 arguments
+`,
+	CodeDuplicateFunc: `
+Two functions have the same name. Every function in a program needs a
+unique name, rc has no overloading.
+`,
+	CodeDuplicateParam: `
+A function has two parameters with the same name:
+
+    fn f(a: i32, a: i32) -> i32 { return a; }
+
+Rename one of them.
+`,
+	CodeRedeclaredVar: `
+A variable was declared twice in the same block, or a 'let' at the top of
+a function body redeclares a parameter:
+
+    let x = 1;
+    let x = 2;
+
+Assign to the existing variable instead, or declare the new one in a nested
+block, where it shadows the outer one until the block ends.
+`,
+	CodeInvalidAssignTarget: `
+The left side of an assignment isn't something that can be assigned to,
+e.g. '1 = x' or 'a + b = c'. Only variables can be assigned to.
+`,
+	CodeAssignLoopVar: `
+The variable of a 'for' loop was assigned to in the loop body. The loop
+advances the variable itself, so changing it would skip or repeat
+iterations. Copy it into a new variable to change it:
+
+    for i in 0..10 {
+        let j = i;
+        j += 1;
+    }
+`,
+	CodeJumpOutsideLoop: `
+'break' or 'continue' was used outside of any loop. They can only appear in
+the body of a 'while', 'loop' or 'for'.
+`,
+	CodeUndeclaredLabel: `
+'break' or 'continue' names a label that no enclosing loop has. Labels are
+declared in front of a loop and are only visible in its body:
+
+    outer: loop {
+        loop { break outer; }
+    }
+`,
+	CodeUnexpectedChar: `
+The source contains characters that aren't part of any token of rc, e.g.
+'@' or '$', or a single '.', which only appears in the range operators '..'
+and '..='. Characters other than letters and digits are only allowed in
+comments.
+`,
+	CodeUnterminatedComment: `
+A block comment started with '/*' is never closed with '*/', so it runs
+to the end of the file. Block comments don't nest: the first '*/' closes
+the comment.
+`,
+	CodeUnknownType: `
+A type name isn't one of the built-in types. rc has signed integers of 8,
+16, 32 and 64 bits, i8 to i64, unsigned ones, u8 to u64, and the pointer
+sized isize and usize:
+
+    let x: int = 1; // should be i32
+`,
+	CodeLiteralOutOfRange: `
+An integer literal is outside the range of the type it takes. A literal
+has the type its context expects, e.g. the declared type of a variable or
+the other operand of '+', and i32 when nothing does:
+
+    let x: u8 = 256;     // u8 holds 0 to 255
+    let y = 3000000000;  // too large for i32, annotate 'let y: i64'
+`,
+	CodeMismatchedTypes: `
+A value of one integer type was used where another is expected. rc never
+converts between integer types implicitly, both operands of an operator
+have to have the same type, and so do a variable and the values assigned
+to it. Convert with 'as':
+
+    let a: u8 = 1;
+    let b: i32 = 2;
+    return a as i32 + b;
+
+A conversion to a narrower type keeps the low bits, so '300 as u8' is 44.
+
+Comparisons and the logical operators '&&' and '||' give a bool, which
+isn't an integer. '&&', '||' and the conditions of 'if' and 'while' only
+take bools. Compare an integer with zero to use it as one:
+
+    while n { n -= 1; }      // should be while n != 0
+`,
+	CodeInvalidOperand: `
+An operator was applied to a value of a type it doesn't work on.
+Arithmetic and the comparisons '<', '<=', '>' and '>=' only work on
+integers, and '-' only on signed ones:
+
+    let n: u32 = 5;
+    let m = -n;          // convert first: -(n as i64)
+    let k = (n > 0) + 1; // comparisons give a bool, not an integer
+
+'!' negates a bool and '~' flips the bits of an integer, neither works on
+the other type:
+
+    let z = !n;          // should be n == 0, or ~n for the bits
+`,
+	CodeMissingReturn: `
+A function can reach the end of its body without returning a value:
+
+    fn sign(n: i32) -> i32 {
+        if n < 0 { return -1; }
+        if n > 0 { return 1; }
+    }                   // nothing is returned for 0
+
+The body returns if it has a 'return' statement, an 'if' with an 'else'
+where both branches return, or a 'loop' that is never left with 'break'.
+Conditions aren't evaluated, so a 'while' loop never counts, even with a
+constant condition: use 'loop' for a loop that only ends by returning.
+`,
+	CodeInvalidCast: `
+An 'as' conversion from an integer to bool was attempted. It would have to
+decide which integers are true, so rc leaves that to an explicit
+comparison:
+
+    let b = n as bool;   // should be n != 0
+
+The other way round is fine: 'true as i32' is 1 and 'false as i32' is 0.
+`,
+	CodeShiftOutOfRange: `
+A value was shifted by a constant amount that is negative or not less than
+the width of its type:
+
+    let x: u8 = 1;
+    let y = x << 8;     // u8 is 8 bits wide, shift by at most 7
+    let z = 1 << 32;    // 1 is an i32 here, write 1 as i64 << 32
+
+The result of such a shift isn't defined in C, which rc compiles to.
+`,
+	CodeDivisionByZero: `
+A value was divided by a constant zero, or its remainder taken:
+
+    let x = n / 0;
+    let y = n % (2 - 2);  // constant arithmetic on literals is worked out
+
+Such a program would abort as soon as it got there. A divisor that is only
+known when the program runs is checked then: dividing by zero aborts with
+'attempt to divide by zero' and the location of the division.
+`,
+	CodeAssignInExpr: `
+An assignment was used inside another expression. Assignments are
+statements in rc and have no value, so the order of an assignment and the
+reads around it is never in question:
+
+    return x + (x = 1);  // assign first: x = 1; return x + x;
+    a = b = 0;           // should be b = 0; a = b;
+`,
+	CodeInvalidMain: `
+The program has no 'main' function, or its 'main' has the wrong signature.
+The program starts at 'main', which takes no parameters and returns the
+exit code of the program:
+
+    fn main() -> i32 {
+        return 0;
+    }
+`,
+	CodeUnusedVariable: `
+A variable declared with 'let' is never read. Assigning to it doesn't count
+as a use. Remove the variable, or start its name with an underscore if it's
+meant to be unused:
+
+    let _unused = f();
+
+This warning is called 'unused-variable'. It can be switched off with
+'-A unused-variable' or made an error with '-D unused-variable'.
+`,
+}
+
+// Explain returns a longer explanation of what causes diagnostics with
+// the given code and how to fix them.
+func Explain(code Code) (string, bool) {
+	text, ok := explanations[Code(strings.ToUpper(string(code)))]
+	return strings.TrimPrefix(text, "\n"), ok
+}
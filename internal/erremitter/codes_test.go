@@ -0,0 +1,18 @@
+package erremitter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+)
+
+func TestExplain(t *testing.T) {
+	text, ok := erremitter.Explain("e0002")
+	if !ok || !strings.Contains(text, "'++'") {
+		t.Errorf("Expected the explanation of E0002, got %q", text)
+	}
+	if _, ok := erremitter.Explain("E9999"); ok {
+		t.Errorf("Expected no explanation for an unknown code")
+	}
+}
@@ -1,53 +1,180 @@
 package erremitter
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
 
 const (
-	maxErrors = 20
+	defaultMaxErrors = 20
 )
 
 type ErrScope struct {
+	File  source.FileID
 	Start int
 	End   int
 }
 
 type SquiggleScope struct {
+	File  source.FileID
 	Start int
 	End   int
 	Lines int
 }
 
+// ErrType is the severity of a diagnostic.
 type ErrType int32
 
+const (
+	Error ErrType = iota
+	Warning
+	Note
+	Help
+)
+
+var errTypeNames = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+	Help:    "help",
+}
+
+func (t ErrType) String() string {
+	return errTypeNames[t]
+}
+
 var (
 	ErrMaxReached = errors.New("maximum number of errors reached")
 )
 
 type Err struct {
+	Severity ErrType
+	// Code identifies the kind of error, see Explain. It is empty for
+	// errors added with AddErr.
+	Code      Code
 	Message   string
 	ErrScope  ErrScope
 	Squiggles []SquiggleScope
+	// Children are the notes and help messages attached to the error.
+	Children []Child
 }
 
+// Child is a note or help message attached to an Err. Its squiggles point
+// at the code the message is about, and may be empty.
+type Child struct {
+	Severity  ErrType
+	Message   string
+	Squiggles []SquiggleScope
+}
+
+// LintLevel decides what happens to a warning with a given code.
+type LintLevel int32
+
+const (
+	// Warn reports the warning as is. It is the default for every code.
+	Warn LintLevel = iota
+	// Allow drops the warning.
+	Allow
+	// Deny reports the warning as an error.
+	Deny
+)
+
 type ErrEmitter struct {
 	errors []Err
+	// maxErrors is the number of errors after which the emitter stops
+	// accepting diagnostics, 0 for no limit. Warnings don't count.
+	maxErrors        int
+	limitReached     bool
+	errCount         int
+	warnCount        int
+	lintLevels       map[Code]LintLevel
+	warningsAsErrors bool
 }
 
 func NewErrEmitter() ErrEmitter {
 	return ErrEmitter{
-		errors: make([]Err, 0, maxErrors),
+		errors:     make([]Err, 0, defaultMaxErrors),
+		maxErrors:  defaultMaxErrors,
+		lintLevels: make(map[Code]LintLevel),
 	}
 }
 
+// SetMaxErrors sets the error limit, 0 meaning unlimited.
+func (ee *ErrEmitter) SetMaxErrors(n int) {
+	ee.maxErrors = n
+}
+
+func (ee *ErrEmitter) SetLintLevel(code Code, level LintLevel) {
+	ee.lintLevels[code] = level
+}
+
+// SetWarningsAsErrors makes every warning that isn't allowed an error.
+func (ee *ErrEmitter) SetWarningsAsErrors(on bool) {
+	ee.warningsAsErrors = on
+}
+
+// AddErr adds an error without a code or children.
 func (ee *ErrEmitter) AddErr(message string, errScope ErrScope, squiggleScopes []SquiggleScope) error {
-	if len(ee.errors) >= maxErrors {
+	return ee.Add(Err{Severity: Error, Message: message, ErrScope: errScope, Squiggles: squiggleScopes})
+}
+
+// Add adds a diagnostic, applying the lint levels to warnings. Once the
+// error limit is reached, nothing is added anymore and ErrMaxReached is
+// returned, so callers can stop early.
+func (ee *ErrEmitter) Add(err Err) error {
+	if err.Severity == Warning {
+		switch {
+		case ee.lintLevels[err.Code] == Allow:
+			return nil
+		case ee.lintLevels[err.Code] == Deny:
+			err.Severity = Error
+			err.Children = append(err.Children, Child{Severity: Note, Message: fmt.Sprintf("warning %s is denied, so it is reported as an error", err.Code)})
+		case ee.warningsAsErrors:
+			err.Severity = Error
+			err.Children = append(err.Children, Child{Severity: Note, Message: "warnings are treated as errors"})
+		}
+	}
+
+	if ee.limitReached {
 		return ErrMaxReached
 	}
-	ee.errors = append(ee.errors, Err{message, errScope, squiggleScopes})
+	switch err.Severity {
+	case Error:
+		if ee.maxErrors > 0 && ee.errCount >= ee.maxErrors {
+			ee.limitReached = true
+			return ErrMaxReached
+		}
+		ee.errCount++
+	case Warning:
+		ee.warnCount++
+	}
+	ee.errors = append(ee.errors, err)
 
 	return nil
 }
 
+// Errors returns all the diagnostics added so far, whatever their
+// severity.
 func (ee *ErrEmitter) Errors() []Err {
 	return ee.errors
 }
+
+func (ee *ErrEmitter) ErrorCount() int {
+	return ee.errCount
+}
+
+func (ee *ErrEmitter) WarningCount() int {
+	return ee.warnCount
+}
+
+// LimitReached reports whether diagnostics were dropped because of the
+// error limit.
+func (ee *ErrEmitter) LimitReached() bool {
+	return ee.limitReached
+}
+
+func (ee *ErrEmitter) MaxErrors() int {
+	return ee.maxErrors
+}
@@ -0,0 +1,66 @@
+package erremitter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+)
+
+func warning(code erremitter.Code) erremitter.Err {
+	return erremitter.Err{Severity: erremitter.Warning, Code: code, Message: "warning"}
+}
+
+func TestErrorLimit(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	em.SetMaxErrors(2)
+
+	for i := range 3 {
+		err := em.AddErr("error", erremitter.ErrScope{}, nil)
+		if i < 2 && err != nil {
+			t.Fatalf("Expected error %d to be added, got %v", i, err)
+		}
+		if i == 2 && !errors.Is(err, erremitter.ErrMaxReached) {
+			t.Fatalf("Expected ErrMaxReached, got %v", err)
+		}
+	}
+	if em.ErrorCount() != 2 || !em.LimitReached() {
+		t.Errorf("Expected 2 errors and the limit reached, got %d errors", em.ErrorCount())
+	}
+}
+
+func TestNoErrorLimit(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	em.SetMaxErrors(0)
+
+	for range 100 {
+		if err := em.AddErr("error", erremitter.ErrScope{}, nil); err != nil {
+			t.Fatalf("Expected no limit, got %v", err)
+		}
+	}
+}
+
+func TestLintLevels(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	em.SetLintLevel("W0001", erremitter.Allow)
+	em.SetLintLevel("W0002", erremitter.Deny)
+
+	em.Add(warning("W0001"))
+	em.Add(warning("W0002"))
+	em.Add(warning("W0003"))
+	if em.ErrorCount() != 1 || em.WarningCount() != 1 || len(em.Errors()) != 2 {
+		t.Errorf("Expected 1 error and 1 warning, got %d and %d", em.ErrorCount(), em.WarningCount())
+	}
+}
+
+func TestWarningsAsErrors(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	em.SetWarningsAsErrors(true)
+	em.SetLintLevel("W0001", erremitter.Allow)
+
+	em.Add(warning("W0001"))
+	em.Add(warning("W0002"))
+	if em.ErrorCount() != 1 || em.WarningCount() != 0 {
+		t.Errorf("Expected 1 error and no warnings, got %d and %d", em.ErrorCount(), em.WarningCount())
+	}
+}
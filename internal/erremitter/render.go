@@ -0,0 +1,215 @@
+package erremitter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorLine  = "\x1b[1;34m"
+)
+
+var severityColors = [...]string{
+	Error:   "\x1b[1;31m",
+	Warning: "\x1b[1;33m",
+	Note:    "\x1b[1;36m",
+	Help:    "\x1b[1;32m",
+}
+
+// Renderer prints errors as a file:line:col header followed by the source
+// lines of every squiggle, underlined with ^~~~. Columns in headers are
+// display columns, see source.Position.
+type Renderer struct {
+	files *source.FileSet
+	color bool
+}
+
+func NewRenderer(files *source.FileSet, color bool) *Renderer {
+	return &Renderer{files: files, color: color}
+}
+
+func (r *Renderer) Report(w io.Writer, errs []Err) error {
+	for _, err := range errs {
+		if e := r.Render(w, err); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (r *Renderer) Render(w io.Writer, err Err) error {
+	var sb strings.Builder
+
+	gutter := r.gutterWidth(err.Squiggles)
+	for _, child := range err.Children {
+		gutter = max(gutter, r.gutterWidth(child.Squiggles))
+	}
+
+	title := err.Severity.String()
+	if err.Code != "" {
+		title += "[" + string(err.Code) + "]"
+	}
+	r.header(&sb, err.ErrScope.File, err.ErrScope.Start, err.Severity, title, err.Message)
+	r.snippet(&sb, err.Squiggles, err.Severity, gutter)
+
+	// Children pointing at code get a snippet of their own, the others are
+	// appended to the snippet of the error.
+	for _, child := range err.Children {
+		if len(child.Squiggles) > 0 {
+			r.header(&sb, child.Squiggles[0].File, child.Squiggles[0].Start, child.Severity, child.Severity.String(), child.Message)
+			r.snippet(&sb, child.Squiggles, child.Severity, gutter)
+			continue
+		}
+		sb.WriteString(r.paint(colorLine, strings.Repeat(" ", gutter)+" = "))
+		sb.WriteString(r.paint(colorBold, child.Severity.String()+":"))
+		sb.WriteString(" " + child.Message + "\n")
+	}
+
+	_, e := io.WriteString(w, sb.String())
+	return e
+}
+
+func (r *Renderer) header(sb *strings.Builder, file source.FileID, offset int, severity ErrType, title, message string) {
+	if f := r.files.File(file); f != nil {
+		pos := f.Position(offset)
+		sb.WriteString(r.paint(colorBold, fmt.Sprintf("%s:%d:%d: ", f.Path, pos.Line, pos.DisplayColumn)))
+	}
+	sb.WriteString(r.paint(severityColors[severity], title+":"))
+	sb.WriteString(r.paint(colorBold, " "+message))
+	sb.WriteByte('\n')
+}
+
+// snippet prints the source lines of squiggles, each followed by its
+// underline. The first squiggle is painted in the color of severity, the
+// others point at related code.
+func (r *Renderer) snippet(sb *strings.Builder, squiggles []SquiggleScope, severity ErrType, gutter int) {
+	// A squiggle on the line that was just printed only adds an underline
+	// instead of repeating the line.
+	var lastFile *source.File
+	lastLine := 0
+	for i, sq := range squiggles {
+		f := r.files.File(sq.File)
+		if f == nil {
+			continue
+		}
+		color := severityColors[severity]
+		if i > 0 {
+			color = severityColors[Note]
+		}
+
+		start, end := f.Position(sq.Start), f.Position(sq.End)
+		lines := min(max(sq.Lines, 1), f.LineCount()-start.Line+1)
+
+		for ln := start.Line; ln < start.Line+lines; ln++ {
+			text := f.Line(ln)
+			if f != lastFile || ln != lastLine {
+				sb.WriteString(r.paint(colorLine, fmt.Sprintf("%*d | ", gutter, ln)))
+				sb.WriteString(string(text))
+				sb.WriteByte('\n')
+				lastFile, lastLine = f, ln
+			}
+
+			from, to := 1, len(text)
+			if ln == start.Line {
+				from = start.Column
+			}
+			if ln == end.Line {
+				to = end.Column
+			}
+			to = max(min(to, len(text)), from)
+
+			underline := strings.Repeat("~", to-from+1)
+			if ln == start.Line {
+				underline = "^" + underline[1:]
+			}
+			sb.WriteString(r.paint(colorLine, strings.Repeat(" ", gutter)+" | "))
+			sb.WriteString(padding(text, from-1))
+			sb.WriteString(r.paint(color, underline))
+			sb.WriteByte('\n')
+		}
+	}
+}
+
+// gutterWidth returns the width of the widest line number printed for
+// squiggles.
+func (r *Renderer) gutterWidth(squiggles []SquiggleScope) int {
+	width := 0
+	for _, sq := range squiggles {
+		if f := r.files.File(sq.File); f != nil {
+			width = max(width, len(fmt.Sprint(f.Position(sq.Start).Line+max(sq.Lines, 1)-1)))
+		}
+	}
+
+	return width
+}
+
+// Summary writes what is left to say after the diagnostics of ee were
+// reported: whether some were dropped because of the error limit, and how
+// many errors and warnings there were.
+func (r *Renderer) Summary(w io.Writer, ee *ErrEmitter) error {
+	var sb strings.Builder
+	if ee.LimitReached() {
+		sb.WriteString(r.paint(severityColors[Error], "error:"))
+		sb.WriteString(r.paint(colorBold, " "+limitMessage(ee.MaxErrors())))
+		sb.WriteByte('\n')
+	}
+
+	var counts []string
+	if n := ee.ErrorCount(); n > 0 {
+		counts = append(counts, plural(n, "error"))
+	}
+	if n := ee.WarningCount(); n > 0 {
+		counts = append(counts, plural(n, "warning"))
+	}
+	if len(counts) > 0 {
+		sb.WriteString(strings.Join(counts, " and ") + " emitted\n")
+	}
+
+	_, e := io.WriteString(w, sb.String())
+	return e
+}
+
+// limitMessage says that no more than maxErrors errors were reported.
+func limitMessage(maxErrors int) string {
+	return fmt.Sprintf("too many errors, stopping after %d", maxErrors)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func (r *Renderer) paint(color, s string) string {
+	if !r.color {
+		return s
+	}
+
+	return color + s + colorReset
+}
+
+// padding returns whitespace as wide as the first n runes of text. Tabs are
+// kept, so the underline lines up whatever the tab width is.
+func padding(text []rune, n int) string {
+	var sb strings.Builder
+	for _, ch := range text[:min(n, len(text))] {
+		if ch == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+	for range n - len(text) {
+		sb.WriteRune(' ')
+	}
+
+	return sb.String()
+}
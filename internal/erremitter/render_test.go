@@ -0,0 +1,90 @@
+package erremitter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+func render(src string, err erremitter.Err) string {
+	var sb strings.Builder
+	files := source.NewFileSet()
+	files.Add("main.rc", []rune(src))
+	r := erremitter.NewRenderer(files, false)
+	r.Report(&sb, []erremitter.Err{err})
+	return sb.String()
+}
+
+func TestRenderSquiggle(t *testing.T) {
+	src := "fn main() -> i32 {\n\treturn foo;\n}\n"
+	got := render(src, erremitter.Err{
+		Message:   "undeclared variable 'foo'",
+		ErrScope:  erremitter.ErrScope{Start: 27, End: 29},
+		Squiggles: []erremitter.SquiggleScope{{Start: 27, End: 29, Lines: 1}},
+	})
+	expected := "main.rc:2:16: error: undeclared variable 'foo'\n" +
+		"2 | \treturn foo;\n" +
+		"  | \t       ^~~\n"
+	if got != expected {
+		t.Errorf("Expected: %q, got %q", expected, got)
+	}
+}
+
+func TestRenderMultiLineSquiggle(t *testing.T) {
+	src := "let x = a +\n  b;\n"
+	got := render(src, erremitter.Err{
+		Message:   "bad",
+		ErrScope:  erremitter.ErrScope{Start: 8, End: 14},
+		Squiggles: []erremitter.SquiggleScope{{Start: 8, End: 14, Lines: 2}},
+	})
+	expected := "main.rc:1:9: error: bad\n" +
+		"1 | let x = a +\n" +
+		"  |         ^~~\n" +
+		"2 |   b;\n" +
+		"  | ~~~\n"
+	if got != expected {
+		t.Errorf("Expected: %q, got %q", expected, got)
+	}
+}
+
+func TestRenderSquiggleAtEndOfFile(t *testing.T) {
+	src := "fn main() -> i32 { return 0"
+	got := render(src, erremitter.Err{
+		Message:   "expected ';'",
+		ErrScope:  erremitter.ErrScope{Start: len(src), End: len(src)},
+		Squiggles: []erremitter.SquiggleScope{{Start: len(src), End: len(src), Lines: 1}},
+	})
+	expected := "main.rc:1:28: error: expected ';'\n" +
+		"1 | fn main() -> i32 { return 0\n" +
+		"  |                            ^\n"
+	if got != expected {
+		t.Errorf("Expected: %q, got %q", expected, got)
+	}
+}
+
+func TestRenderCodeAndChildren(t *testing.T) {
+	src := "let b = 1;\nlet b = 2;\n"
+	got := render(src, erremitter.Err{
+		Severity:  erremitter.Error,
+		Code:      erremitter.CodeRedeclaredVar,
+		Message:   "variable 'b' is already declared in this scope",
+		ErrScope:  erremitter.ErrScope{Start: 15, End: 15},
+		Squiggles: []erremitter.SquiggleScope{{Start: 15, End: 15, Lines: 1}},
+		Children: []erremitter.Child{
+			{Severity: erremitter.Note, Message: "previous declaration is here", Squiggles: []erremitter.SquiggleScope{{Start: 4, End: 4, Lines: 1}}},
+			{Severity: erremitter.Help, Message: "assign to it instead"},
+		},
+	})
+	expected := "main.rc:2:5: error[E0010]: variable 'b' is already declared in this scope\n" +
+		"2 | let b = 2;\n" +
+		"  |     ^\n" +
+		"main.rc:1:5: note: previous declaration is here\n" +
+		"1 | let b = 1;\n" +
+		"  |     ^\n" +
+		"  = help: assign to it instead\n"
+	if got != expected {
+		t.Errorf("Expected: %q, got %q", expected, got)
+	}
+}
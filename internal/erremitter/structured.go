@@ -0,0 +1,287 @@
+package erremitter
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "rc"
+	toolURI      = "https://github.com/Mixturka/rc"
+)
+
+// Reporter writes the errors of a single source file in some output
+// format.
+type Reporter interface {
+	Report(w io.Writer, errs []Err) error
+}
+
+// Span is a range of source text. Lines and columns are 1-based and the
+// end column points past the last character, as in SARIF.
+type Span struct {
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+}
+
+type jsonErr struct {
+	File     string `json:"file"`
+	Severity string `json:"severity"`
+	Code     Code   `json:"code,omitempty"`
+	Message  string `json:"message"`
+	Span
+	Children []jsonChild `json:"children,omitempty"`
+}
+
+// jsonChild is a note or help message. The span is that of its first
+// squiggle and is left out when it has none.
+type jsonChild struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	*Span
+}
+
+// jsonLimit is the record that ends the stream when errors were dropped
+// because of the error limit.
+type jsonLimit struct {
+	Message   string `json:"message"`
+	MaxErrors int    `json:"max_errors"`
+}
+
+// JSONReporter writes every error as a JSON object on its own line.
+// Columns count Unicode code points.
+type JSONReporter struct {
+	files     *source.FileSet
+	maxErrors int
+}
+
+func NewJSONReporter(files *source.FileSet) *JSONReporter {
+	return &JSONReporter{files: files}
+}
+
+// SetLimitReached records that errors after the first maxErrors were
+// dropped. A last line then says so, with the limit in 'max_errors'.
+func (jr *JSONReporter) SetLimitReached(maxErrors int) {
+	jr.maxErrors = maxErrors
+}
+
+func (jr *JSONReporter) Report(w io.Writer, errs []Err) error {
+	enc := json.NewEncoder(w)
+	for _, err := range errs {
+		e := jsonErr{
+			File:     fileURI(jr.files, err.ErrScope.File),
+			Severity: err.Severity.String(),
+			Code:     err.Code,
+			Message:  err.Message,
+			Span:     span(jr.files, err.ErrScope.File, err.ErrScope.Start, err.ErrScope.End),
+		}
+		for _, child := range err.Children {
+			c := jsonChild{Severity: child.Severity.String(), Message: child.Message}
+			if len(child.Squiggles) > 0 {
+				sq := child.Squiggles[0]
+				span := span(jr.files, sq.File, sq.Start, sq.End)
+				c.Span = &span
+			}
+			e.Children = append(e.Children, c)
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if jr.maxErrors > 0 {
+		return enc.Encode(jsonLimit{Message: limitMessage(jr.maxErrors), MaxErrors: jr.maxErrors})
+	}
+
+	return nil
+}
+
+var sarifLevels = [...]string{
+	Error:   "error",
+	Warning: "warning",
+	Note:    "note",
+	Help:    "note",
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	ColumnKind  string            `json:"columnKind"`
+	Results     []sarifResult     `json:"results"`
+}
+
+// sarifInvocation describes the run of rc. It only fails to complete when
+// the error limit is reached, which a notification then explains.
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifResult struct {
+	RuleID           Code            `json:"ruleId,omitempty"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// SARIFReporter writes all errors as a single SARIF 2.1.0 log. Squiggles
+// other than the first one, and the squiggles of notes and help messages,
+// become related locations. Children without squiggles are appended to the
+// message text.
+type SARIFReporter struct {
+	files     *source.FileSet
+	maxErrors int
+}
+
+func NewSARIFReporter(files *source.FileSet) *SARIFReporter {
+	return &SARIFReporter{files: files}
+}
+
+// SetLimitReached records that errors after the first maxErrors were
+// dropped. The invocation in the log then isn't successful and has a
+// notification saying so.
+func (sr *SARIFReporter) SetLimitReached(maxErrors int) {
+	sr.maxErrors = maxErrors
+}
+
+func (sr *SARIFReporter) Report(w io.Writer, errs []Err) error {
+	results := make([]sarifResult, 0, len(errs))
+	for _, err := range errs {
+		result := sarifResult{
+			RuleID:    err.Code,
+			Level:     sarifLevels[err.Severity],
+			Message:   sarifMessage{Text: err.Message},
+			Locations: []sarifLocation{sr.location(err.ErrScope.File, err.ErrScope.Start, err.ErrScope.End)},
+		}
+		for i, sq := range err.Squiggles {
+			if i > 0 {
+				result.RelatedLocations = append(result.RelatedLocations, sr.location(sq.File, sq.Start, sq.End))
+			}
+		}
+		for _, child := range err.Children {
+			text := child.Severity.String() + ": " + child.Message
+			if len(child.Squiggles) == 0 {
+				result.Message.Text += "\n" + text
+				continue
+			}
+			for _, sq := range child.Squiggles {
+				loc := sr.location(sq.File, sq.Start, sq.End)
+				loc.Message = &sarifMessage{Text: text}
+				result.RelatedLocations = append(result.RelatedLocations, loc)
+			}
+		}
+		results = append(results, result)
+	}
+
+	invocation := sarifInvocation{ExecutionSuccessful: true}
+	if sr.maxErrors > 0 {
+		invocation.ExecutionSuccessful = false
+		invocation.ToolExecutionNotifications = []sarifNotification{{
+			Level:   sarifLevels[Error],
+			Message: sarifMessage{Text: limitMessage(sr.maxErrors)},
+		}}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool:        sarifTool{Driver: sarifDriver{Name: toolName, InformationURI: toolURI}},
+			Invocations: []sarifInvocation{invocation},
+			ColumnKind:  "unicodeCodePoints",
+			Results:     results,
+		}},
+	})
+}
+
+func (sr *SARIFReporter) location(file source.FileID, start, end int) sarifLocation {
+	span := span(sr.files, file, start, end)
+	return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: fileURI(sr.files, file)},
+		Region: sarifRegion{
+			StartLine:   span.StartLine,
+			StartColumn: span.StartColumn,
+			EndLine:     span.EndLine,
+			EndColumn:   span.EndColumn,
+		},
+	}}
+}
+
+// span converts the inclusive offsets start and end into file into a
+// Span.
+func span(files *source.FileSet, file source.FileID, start, end int) Span {
+	f := files.File(file)
+	if f == nil {
+		return Span{}
+	}
+	startPos, endPos := f.Position(start), f.Position(max(end, start))
+
+	return Span{StartLine: startPos.Line, StartColumn: startPos.Column, EndLine: endPos.Line, EndColumn: endPos.Column + 1}
+}
+
+func fileURI(files *source.FileSet, file source.FileID) string {
+	f := files.File(file)
+	if f == nil {
+		return ""
+	}
+	path := f.Path
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,119 @@
+package erremitter_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+var multiLineErr = erremitter.Err{
+	Message:  "call to undefined function 'foo'",
+	Code:     "E0001",
+	ErrScope: erremitter.ErrScope{Start: 27, End: 36},
+	Squiggles: []erremitter.SquiggleScope{
+		{Start: 27, End: 36, Lines: 2},
+		{Start: 3, End: 6, Lines: 1},
+	},
+}
+
+const multiLineSrc = "fn main() -> i32 {\n\treturn foo(1,\n 2);\n}\n"
+
+func multiLineFiles() *source.FileSet {
+	files := source.NewFileSet()
+	files.Add("/src/main.rc", []rune(multiLineSrc))
+	return files
+}
+
+func TestJSONReport(t *testing.T) {
+	var sb strings.Builder
+	r := erremitter.NewJSONReporter(multiLineFiles())
+	if err := r.Report(&sb, []erremitter.Err{multiLineErr, multiLineErr}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"file":"file:///src/main.rc","severity":"error","code":"E0001","message":"call to undefined function 'foo'",` +
+		`"start_line":2,"start_column":9,"end_line":3,"end_column":4}` + "\n"
+	if got := sb.String(); got != expected+expected {
+		t.Errorf("Expected: %v, got %v", expected+expected, got)
+	}
+}
+
+func TestSARIFReport(t *testing.T) {
+	var sb strings.Builder
+	r := erremitter.NewSARIFReporter(multiLineFiles())
+	if err := r.Report(&sb, []erremitter.Err{multiLineErr}); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID           string
+				Level            string
+				Locations        []json.RawMessage
+				RelatedLocations []json.RawMessage
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("Expected a 2.1.0 log with one result, got %v", sb.String())
+	}
+	result := log.Runs[0].Results[0]
+	if result.RuleID != "E0001" || result.Level != "error" || len(result.Locations) != 1 || len(result.RelatedLocations) != 1 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestJSONReportLimitReached(t *testing.T) {
+	var sb strings.Builder
+	r := erremitter.NewJSONReporter(multiLineFiles())
+	r.SetLimitReached(1)
+	if err := r.Report(&sb, []erremitter.Err{multiLineErr}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	expected := `{"message":"too many errors, stopping after 1","max_errors":1}`
+	if len(lines) != 2 || lines[1] != expected {
+		t.Errorf("Expected a last line %v, got %v", expected, sb.String())
+	}
+}
+
+func TestSARIFReportLimitReached(t *testing.T) {
+	var sb strings.Builder
+	r := erremitter.NewSARIFReporter(multiLineFiles())
+	r.SetLimitReached(1)
+	if err := r.Report(&sb, []erremitter.Err{multiLineErr}); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Runs []struct {
+			Invocations []struct {
+				ExecutionSuccessful        bool
+				ToolExecutionNotifications []struct {
+					Level   string
+					Message struct{ Text string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if len(log.Runs) != 1 || len(log.Runs[0].Invocations) != 1 {
+		t.Fatalf("Expected one run with one invocation, got %v", sb.String())
+	}
+	invocation := log.Runs[0].Invocations[0]
+	if invocation.ExecutionSuccessful || len(invocation.ToolExecutionNotifications) != 1 ||
+		invocation.ToolExecutionNotifications[0].Message.Text != "too many errors, stopping after 1" {
+		t.Errorf("Unexpected invocation %+v", invocation)
+	}
+}
@@ -1,11 +1,15 @@
 package lexer
 
 import (
+	"fmt"
 	"slices"
+	"strings"
 	"unicode"
 
+	"github.com/Mixturka/rc/internal/erremitter"
 	"github.com/Mixturka/rc/internal/lexer/token"
 	"github.com/Mixturka/rc/internal/pkg/scope"
+	"github.com/Mixturka/rc/internal/pkg/source"
 )
 
 type LexerError int
@@ -27,41 +31,53 @@ func (le LexerError) Error() string {
 	return ""
 }
 
+// punctuation holds the characters that start operators and delimiters.
+const punctuation = "(){}:;,*-+/=!~%&|^<>."
+
 type Lexer struct {
-	src  []rune
-	pos  int
-	line int
+	src        []rune
+	file       source.FileID
+	pos        int
+	line       int
+	errEmitter *erremitter.ErrEmitter
 }
 
-func NewLexer(src []rune) *Lexer {
+// NewLexer returns a lexer for src, whose scopes refer to file 0.
+func NewLexer(src []rune, errEmitter *erremitter.ErrEmitter) *Lexer {
 	return &Lexer{
-		src:  src,
-		line: 1,
+		src:        src,
+		line:       1,
+		errEmitter: errEmitter,
 	}
 }
 
-func (l *Lexer) Tokenize() (tokens []token.Token, err error) {
+func NewFileLexer(file *source.File, errEmitter *erremitter.ErrEmitter) *Lexer {
+	l := NewLexer(file.Src, errEmitter)
+	l.file = file.ID
+	return l
+}
+
+// Tokenize splits the source into tokens, ending with Eof. Lexical errors
+// are reported through the error emitter and produce Illegal tokens, so the
+// whole source is always tokenized.
+func (l *Lexer) Tokenize() (tokens []token.Token) {
 	for l.pos < len(l.src) {
-		if tok, err := l.scanToken(); err != nil {
-			if err == CommentSkipped || err == NewLineSkipped || err == WhitespaceSkipped || err == TabSkipped {
-				continue
-			}
-			return nil, err
-		} else {
+		// Errors only signal skipped whitespace and comments here.
+		if tok, err := l.scanToken(); err == nil {
 			tokens = append(tokens, tok)
 		}
 	}
 	tokens = append(tokens, token.Token{
 		Type:  token.Eof,
-		Scope: scope.Scope{Start: len(l.src), End: len(l.src), Line: l.line},
+		Scope: scope.Scope{Start: len(l.src), End: len(l.src), Line: l.line, File: l.file},
 	})
 
-	return tokens, nil
+	return tokens
 }
 
 func (l *Lexer) scanToken() (token.Token, error) {
 	ch := l.src[l.pos]
-	scope := scope.Scope{Start: l.pos, End: l.pos, Line: l.line}
+	scope := scope.Scope{Start: l.pos, End: l.pos, Line: l.line, File: l.file}
 	l.pos++
 
 	switch ch {
@@ -77,6 +93,8 @@ func (l *Lexer) scanToken() (token.Token, error) {
 		return token.Token{Type: token.Colon, Scope: scope}, nil
 	case ';':
 		return token.Token{Type: token.Semicolon, Scope: scope}, nil
+	case ',':
+		return token.Token{Type: token.Comma, Scope: scope}, nil
 	case '*':
 		if tok, ok := l.expectNext(ExpectedInfo{'=', token.StarAssign}); ok {
 			return tok, nil
@@ -104,7 +122,10 @@ func (l *Lexer) scanToken() (token.Token, error) {
 				return token.Token{}, CommentSkipped
 			case '*':
 				l.pos++
-				l.skipMultiLineComment()
+				if !l.skipMultiLineComment() {
+					scope.End = scope.Start + 1
+					l.reportErr(erremitter.CodeUnterminatedComment, "unterminated block comment, '*/' is missing", scope, 1)
+				}
 				return token.Token{}, CommentSkipped
 			default:
 				return token.Token{Type: token.Slash, Scope: scope}, nil
@@ -126,25 +147,46 @@ func (l *Lexer) scanToken() (token.Token, error) {
 	case '%':
 		return token.Token{Type: token.Percent, Scope: scope}, nil
 	case '&':
-		if tok, ok := l.expectNext(ExpectedInfo{'&', token.AmpersandAmpersand}); ok {
+		if tok, ok := l.expectNext(ExpectedInfo{'&', token.AmpersandAmpersand}, ExpectedInfo{'=', token.AmpersandAssign}); ok {
 			return tok, nil
 		}
 		return token.Token{Type: token.Ampersand, Scope: scope}, nil
 	case '|':
-		if tok, ok := l.expectNext(ExpectedInfo{'|', token.BarBar}); ok {
+		if tok, ok := l.expectNext(ExpectedInfo{'|', token.BarBar}, ExpectedInfo{'=', token.BarAssign}); ok {
 			return tok, nil
 		}
 		return token.Token{Type: token.Bar, Scope: scope}, nil
+	case '^':
+		if tok, ok := l.expectNext(ExpectedInfo{'=', token.CaretAssign}); ok {
+			return tok, nil
+		}
+		return token.Token{Type: token.Caret, Scope: scope}, nil
 	case '>':
+		if tok, ok := l.expectNext(ExpectedInfo{'>', token.GreaterGreater}); ok {
+			return l.shiftAssign(tok, token.GreaterGreaterAssign), nil
+		}
 		if tok, ok := l.expectNext(ExpectedInfo{'=', token.GreaterEqual}); ok {
 			return tok, nil
 		}
 		return token.Token{Type: token.Greater, Scope: scope}, nil
 	case '<':
+		if tok, ok := l.expectNext(ExpectedInfo{'<', token.LessLess}); ok {
+			return l.shiftAssign(tok, token.LessLessAssign), nil
+		}
 		if tok, ok := l.expectNext(ExpectedInfo{'=', token.LessEqual}); ok {
 			return tok, nil
 		}
 		return token.Token{Type: token.Less, Scope: scope}, nil
+	case '.':
+		if tok, ok := l.expectNext(ExpectedInfo{'.', token.DotDot}); ok {
+			if inclusive, ok := l.expectNext(ExpectedInfo{'=', token.DotDotEqual}); ok {
+				inclusive.Scope.Start = tok.Scope.Start
+				return inclusive, nil
+			}
+			return tok, nil
+		}
+		l.reportErr(erremitter.CodeUnexpectedChar, "unexpected character '.', ranges are written '..' or '..='", scope, 1)
+		return token.Token{Type: token.Illegal, Scope: scope}, nil
 	case '\n':
 		l.line++
 		return token.Token{}, NewLineSkipped
@@ -152,6 +194,8 @@ func (l *Lexer) scanToken() (token.Token, error) {
 		return token.Token{}, WhitespaceSkipped
 	case '\t':
 		return token.Token{}, TabSkipped
+	case '\r':
+		return token.Token{}, WhitespaceSkipped
 	default:
 		switch {
 		case unicode.IsLetter(ch) || ch == '_':
@@ -164,23 +208,75 @@ func (l *Lexer) scanToken() (token.Token, error) {
 				return keyword, nil
 			}
 			return tok, nil
-		case unicode.IsDigit(ch):
+		case isDigit(ch):
 			tok, err := l.scanNumber(scope.Start)
 			if err != nil {
 				return token.Token{}, nil
 			}
 			return tok, nil
 		default:
-			return token.Token{}, UnexpectedSymbol
+			return l.scanIllegal(scope), nil
 		}
 	}
 }
 
+// scanIllegal scans a run of characters that can't start a token, the
+// first of which is already consumed, and reports them as one error.
+func (l *Lexer) scanIllegal(s scope.Scope) token.Token {
+	for l.pos < len(l.src) && !startsToken(l.src[l.pos]) {
+		l.pos++
+	}
+	s.End = l.pos - 1
+
+	text := string(l.src[s.Start:l.pos])
+	message := fmt.Sprintf("unexpected character '%s'", text)
+	if s.End > s.Start {
+		message = fmt.Sprintf("unexpected characters '%s'", text)
+	}
+	l.reportErr(erremitter.CodeUnexpectedChar, message, s, 1)
+
+	return token.Token{Type: token.Illegal, Scope: s}
+}
+
+// startsToken reports whether ch starts a token or is skipped between
+// tokens.
+func startsToken(ch rune) bool {
+	return unicode.IsLetter(ch) || isDigit(ch) || ch == '_' || unicode.IsSpace(ch) || strings.ContainsRune(punctuation, ch)
+}
+
+// isDigit reports whether ch is one of the ASCII digits, the only ones
+// integer literals and identifiers are made of. Other Unicode digits, such
+// as '٣', are unexpected characters.
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func (l *Lexer) reportErr(code erremitter.Code, message string, s scope.Scope, lines int) {
+	l.errEmitter.Add(erremitter.Err{
+		Severity:  erremitter.Error,
+		Code:      code,
+		Message:   message,
+		ErrScope:  erremitter.ErrScope{File: s.File, Start: s.Start, End: s.End},
+		Squiggles: []erremitter.SquiggleScope{{File: s.File, Start: s.Start, End: s.End, Lines: lines}},
+	})
+}
+
 type ExpectedInfo struct {
 	Ch      rune
 	TokType token.TokenType // type to return in case of success
 }
 
+// shiftAssign extends the shift operator shift to the compound assignment
+// of type tt if it is followed by '='.
+func (l *Lexer) shiftAssign(shift token.Token, tt token.TokenType) token.Token {
+	if assign, ok := l.expectNext(ExpectedInfo{'=', tt}); ok {
+		assign.Scope.Start = shift.Scope.Start
+		return assign
+	}
+
+	return shift
+}
+
 func (l *Lexer) expectNext(options ...ExpectedInfo) (token.Token, bool) {
 	if l.pos >= len(l.src) {
 		return token.Token{}, false
@@ -190,7 +286,7 @@ func (l *Lexer) expectNext(options ...ExpectedInfo) (token.Token, bool) {
 	for _, v := range options {
 		if next == v.Ch {
 			l.pos++
-			return token.Token{Type: v.TokType, Scope: scope.Scope{Start: l.pos - 2, End: l.pos - 1, Line: l.line}}, true
+			return token.Token{Type: v.TokType, Scope: scope.Scope{Start: l.pos - 2, End: l.pos - 1, Line: l.line, File: l.file}}, true
 		}
 	}
 
@@ -198,58 +294,64 @@ func (l *Lexer) expectNext(options ...ExpectedInfo) (token.Token, bool) {
 }
 
 func (l *Lexer) skipOneLineComment() {
-	for l.src[l.pos] != '\n' {
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
 		l.pos++
 	}
-	l.pos++
-	l.line++
+	if l.pos < len(l.src) {
+		l.pos++
+		l.line++
+	}
 }
 
-func (l *Lexer) skipMultiLineComment() {
+// skipMultiLineComment skips a block comment whose '/*' is already consumed.
+// It reports whether the closing '*/' was found.
+func (l *Lexer) skipMultiLineComment() bool {
 	for l.pos < len(l.src) {
 		if l.pos < len(l.src)-1 && slices.Equal(l.src[l.pos:l.pos+2], []rune("*/")) {
 			l.pos += 2
-			return
+			return true
 		}
 		if l.src[l.pos] == '\n' {
 			l.line++
 		}
 		l.pos++
 	}
+
+	return false
 }
 
 func (l *Lexer) scanIdentifier(scopeStart int) (token.Token, error) {
 	if l.pos >= len(l.src) {
-		return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: scopeStart, Line: l.line}}, nil
+		return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: scopeStart, Line: l.line, File: l.file}}, nil
 	}
 	ch := l.src[l.pos]
 
-	for l.pos < len(l.src)-1 && (unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_') {
+	for l.pos < len(l.src)-1 && (unicode.IsLetter(ch) || isDigit(ch) || ch == '_') {
 		l.pos++
 		ch = l.src[l.pos]
 	}
-	if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' {
+	if unicode.IsLetter(ch) || isDigit(ch) || ch == '_' {
 		l.pos++
 	}
 
-	return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: l.pos - 1, Line: l.line}}, nil
+	return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: l.pos - 1, Line: l.line, File: l.file}}, nil
 }
 
 func (l *Lexer) scanNumber(scopeStart int) (token.Token, error) {
 	if l.pos >= len(l.src) {
-		return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: scopeStart, Line: l.line}}, nil
+		return token.Token{Type: token.Identifier, Scope: scope.Scope{Start: scopeStart, End: scopeStart, Line: l.line, File: l.file}}, nil
 	}
 	ch := l.src[l.pos]
 
-	for l.pos < len(l.src)-1 && unicode.IsDigit(ch) {
+	for l.pos < len(l.src)-1 && isDigit(ch) {
 		l.pos++
 		ch = l.src[l.pos]
 	}
-	if unicode.IsDigit(ch) {
+	if isDigit(ch) {
 		l.pos++
 	}
 
-	return token.Token{Type: token.IntegerNumber, Scope: scope.Scope{Start: scopeStart, End: l.pos - 1, Line: l.line}}, nil
+	return token.Token{Type: token.IntegerNumber, Scope: scope.Scope{Start: scopeStart, End: l.pos - 1, Line: l.line, File: l.file}}, nil
 }
 
 func (l *Lexer) checkKeyword(tok token.Token) (token.Token, bool) {
@@ -260,6 +362,30 @@ func (l *Lexer) checkKeyword(tok token.Token) (token.Token, bool) {
 		return token.Token{Type: token.Fn, Scope: tok.Scope}, true
 	case "return":
 		return token.Token{Type: token.Return, Scope: tok.Scope}, true
+	case "let":
+		return token.Token{Type: token.Let, Scope: tok.Scope}, true
+	case "if":
+		return token.Token{Type: token.If, Scope: tok.Scope}, true
+	case "else":
+		return token.Token{Type: token.Else, Scope: tok.Scope}, true
+	case "while":
+		return token.Token{Type: token.While, Scope: tok.Scope}, true
+	case "loop":
+		return token.Token{Type: token.Loop, Scope: tok.Scope}, true
+	case "break":
+		return token.Token{Type: token.Break, Scope: tok.Scope}, true
+	case "continue":
+		return token.Token{Type: token.Continue, Scope: tok.Scope}, true
+	case "for":
+		return token.Token{Type: token.For, Scope: tok.Scope}, true
+	case "in":
+		return token.Token{Type: token.In, Scope: tok.Scope}, true
+	case "as":
+		return token.Token{Type: token.As, Scope: tok.Scope}, true
+	case "true":
+		return token.Token{Type: token.True, Scope: tok.Scope}, true
+	case "false":
+		return token.Token{Type: token.False, Scope: tok.Scope}, true
 	default:
 		return token.Token{}, false
 	}
@@ -4,344 +4,568 @@ import (
 	"slices"
 	"testing"
 
+	"github.com/Mixturka/rc/internal/erremitter"
 	"github.com/Mixturka/rc/internal/lexer"
 	"github.com/Mixturka/rc/internal/lexer/token"
 	"github.com/Mixturka/rc/internal/pkg/scope"
+	"github.com/Mixturka/rc/internal/pkg/source"
 )
 
 func TestLexLeftParen(t *testing.T) {
-	l := lexer.NewLexer([]rune{'('})
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune{'('}, &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.LeftParen, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexRightParen(t *testing.T) {
-	l := lexer.NewLexer([]rune{')'})
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune{')'}, &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.RightParen, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexLeftBrace(t *testing.T) {
-	l := lexer.NewLexer([]rune{'{'})
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune{'{'}, &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.LeftBrace, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexRightBrace(t *testing.T) {
-	l := lexer.NewLexer([]rune{'}'})
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune{'}'}, &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.RightBrace, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexArrow(t *testing.T) {
-	l := lexer.NewLexer([]rune("->"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("->"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Arrow, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexColon(t *testing.T) {
-	l := lexer.NewLexer([]rune(":"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(":"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Colon, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexSemicolon(t *testing.T) {
-	l := lexer.NewLexer([]rune(";"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(";"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Semicolon, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexComma(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(","), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Comma, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexStar(t *testing.T) {
-	l := lexer.NewLexer([]rune("*"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("*"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Star, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexMinus(t *testing.T) {
-	l := lexer.NewLexer([]rune("-"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("-"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Minus, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexPlus(t *testing.T) {
-	l := lexer.NewLexer([]rune("+"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("+"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Plus, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexSlash(t *testing.T) {
-	l := lexer.NewLexer([]rune("/"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("/"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Slash, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexSkipOneLineComment(t *testing.T) {
-	l := lexer.NewLexer([]rune("// blah blah blah \n ("))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("// blah blah blah \n ("), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.LeftParen, Scope: scope.Scope{Start: 20, End: 20, Line: 2}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 21, End: 21, Line: 2}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexSkipMulilineComment(t *testing.T) {
-	l := lexer.NewLexer([]rune("/* blah blah blah \n super blah */("))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("/* blah blah blah \n super blah */("), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.LeftParen, Scope: scope.Scope{Start: 33, End: 33, Line: 2}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 34, End: 34, Line: 2}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexMinusAssign(t *testing.T) {
-	l := lexer.NewLexer([]rune("-="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("-="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.MinusAssign, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexMinusMinus(t *testing.T) {
-	l := lexer.NewLexer([]rune("--"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("--"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.MinusMinus, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexPlusAssign(t *testing.T) {
-	l := lexer.NewLexer([]rune("+="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("+="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.PlusAssign, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexPlusPlus(t *testing.T) {
-	l := lexer.NewLexer([]rune("++"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("++"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.PlusPlus, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexStarAssign(t *testing.T) {
-	l := lexer.NewLexer([]rune("*="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("*="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.StarAssign, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexSlashAssign(t *testing.T) {
-	l := lexer.NewLexer([]rune("/="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("/="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.SlashAssign, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexAssign(t *testing.T) {
-	l := lexer.NewLexer([]rune("="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Assign, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexEquals(t *testing.T) {
-	l := lexer.NewLexer([]rune("=="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("=="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Equals, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexNotEquals(t *testing.T) {
-	l := lexer.NewLexer([]rune("!="))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("!="), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.NotEquals, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexNot(t *testing.T) {
-	l := lexer.NewLexer([]rune("!"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("!"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Not, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexDoubleNot(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("!!a != b"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Not, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Not, Scope: scope.Scope{Start: 1, End: 1, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
+		{Type: token.NotEquals, Scope: scope.Scope{Start: 4, End: 5, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 7, End: 7, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 8, End: 8, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexIdentifier1(t *testing.T) {
-	l := lexer.NewLexer([]rune("identifier"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("identifier"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Identifier, Scope: scope.Scope{Start: 0, End: 9, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 10, End: 10, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexIdentifier2(t *testing.T) {
-	l := lexer.NewLexer([]rune("_identifier_"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("_identifier_"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Identifier, Scope: scope.Scope{Start: 0, End: 11, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 12, End: 12, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexIdentifier3(t *testing.T) {
-	l := lexer.NewLexer([]rune("identifier_123_cool"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("identifier_123_cool"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Identifier, Scope: scope.Scope{Start: 0, End: 18, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 19, End: 19, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexDecimalPositiveIntegerNumber(t *testing.T) {
-	l := lexer.NewLexer([]rune("123"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("123"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 0, End: 2, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 3, End: 3, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexDecimalNegativeIntegerNumber(t *testing.T) {
-	l := lexer.NewLexer([]rune("-123"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("-123"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
-		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 0, End: 3, Line: 1}},
+		{Type: token.Minus, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 1, End: 3, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 4, End: 4, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexFnKeyword(t *testing.T) {
-	l := lexer.NewLexer([]rune("fn"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("fn"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Fn, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexReturnKeyword(t *testing.T) {
-	l := lexer.NewLexer([]rune("return"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("return"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Return, Scope: scope.Scope{Start: 0, End: 5, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 6, End: 6, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexLetKeyword(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("let"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Let, Scope: scope.Scope{Start: 0, End: 2, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 3, End: 3, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexIfElseKeywords(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("if else"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.If, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Else, Scope: scope.Scope{Start: 3, End: 6, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 7, End: 7, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexLoopKeywords(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("while loop break continue"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.While, Scope: scope.Scope{Start: 0, End: 4, Line: 1}},
+		{Type: token.Loop, Scope: scope.Scope{Start: 6, End: 9, Line: 1}},
+		{Type: token.Break, Scope: scope.Scope{Start: 11, End: 15, Line: 1}},
+		{Type: token.Continue, Scope: scope.Scope{Start: 17, End: 24, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 25, End: 25, Line: 1}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("for i in 0..10"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.For, Scope: scope.Scope{Start: 0, End: 2, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 4, End: 4, Line: 1}},
+		{Type: token.In, Scope: scope.Scope{Start: 6, End: 7, Line: 1}},
+		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 9, End: 9, Line: 1}},
+		{Type: token.DotDot, Scope: scope.Scope{Start: 10, End: 11, Line: 1}},
+		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 12, End: 13, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 14, End: 14, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexCast(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("x as u8"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Identifier, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.As, Scope: scope.Scope{Start: 2, End: 3, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 5, End: 6, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 7, End: 7, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexBoolLiterals(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("true false truth"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.True, Scope: scope.Scope{Start: 0, End: 3, Line: 1}},
+		{Type: token.False, Scope: scope.Scope{Start: 5, End: 9, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 11, End: 15, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 16, End: 16, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexBitwiseOperators(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("& &= | |= ^ ^= << <<= >> >>= <= >="), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Ampersand, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.AmpersandAssign, Scope: scope.Scope{Start: 2, End: 3, Line: 1}},
+		{Type: token.Bar, Scope: scope.Scope{Start: 5, End: 5, Line: 1}},
+		{Type: token.BarAssign, Scope: scope.Scope{Start: 7, End: 8, Line: 1}},
+		{Type: token.Caret, Scope: scope.Scope{Start: 10, End: 10, Line: 1}},
+		{Type: token.CaretAssign, Scope: scope.Scope{Start: 12, End: 13, Line: 1}},
+		{Type: token.LessLess, Scope: scope.Scope{Start: 15, End: 16, Line: 1}},
+		{Type: token.LessLessAssign, Scope: scope.Scope{Start: 18, End: 20, Line: 1}},
+		{Type: token.GreaterGreater, Scope: scope.Scope{Start: 22, End: 23, Line: 1}},
+		{Type: token.GreaterGreaterAssign, Scope: scope.Scope{Start: 25, End: 27, Line: 1}},
+		{Type: token.LessEqual, Scope: scope.Scope{Start: 29, End: 30, Line: 1}},
+		{Type: token.GreaterEqual, Scope: scope.Scope{Start: 32, End: 33, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 34, End: 34, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexInclusiveRange(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("0..=n"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.DotDotEqual, Scope: scope.Scope{Start: 1, End: 3, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 4, End: 4, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 5, End: 5, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
 
 func TestLexMainFunctionWithOneReturnInteger(t *testing.T) {
-	l := lexer.NewLexer([]rune("fn main() -> i32 {\n\treturn 23;\n}"))
-	toks, err := l.Tokenize()
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("fn main() -> i32 {\n\treturn 23;\n}"), &em)
+	toks := l.Tokenize()
 	correctTokenSlice := []token.Token{
 		{Type: token.Fn, Scope: scope.Scope{Start: 0, End: 1, Line: 1}}, {Type: token.Identifier, Scope: scope.Scope{Start: 3, End: 6, Line: 1}},
 		{Type: token.LeftParen, Scope: scope.Scope{Start: 7, End: 7, Line: 1}}, {Type: token.RightParen, Scope: scope.Scope{Start: 8, End: 8, Line: 1}},
@@ -349,8 +573,110 @@ func TestLexMainFunctionWithOneReturnInteger(t *testing.T) {
 		{Type: token.LeftBrace, Scope: scope.Scope{Start: 17, End: 17, Line: 1}}, {Type: token.Return, Scope: scope.Scope{Start: 20, End: 25, Line: 2}},
 		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 27, End: 28, Line: 2}}, {Type: token.Semicolon, Scope: scope.Scope{Start: 29, End: 29, Line: 2}},
 		{Type: token.RightBrace, Scope: scope.Scope{Start: 31, End: 31, Line: 3}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 32, End: 32, Line: 3}},
 	}
-	if !slices.Equal(toks, correctTokenSlice) || err != nil {
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
 		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
 	}
 }
+
+func TestLexUnexpectedCharacters(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("a @ b$$ . c"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.Identifier, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Illegal, Scope: scope.Scope{Start: 2, End: 2, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 4, End: 4, Line: 1}},
+		{Type: token.Illegal, Scope: scope.Scope{Start: 5, End: 6, Line: 1}},
+		{Type: token.Illegal, Scope: scope.Scope{Start: 8, End: 8, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 10, End: 10, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 11, End: 11, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+
+	var messages []string
+	for _, e := range em.Errors() {
+		messages = append(messages, e.Message)
+	}
+	expected := []string{
+		"unexpected character '@'",
+		"unexpected characters '$$'",
+		"unexpected character '.', ranges are written '..' or '..='",
+	}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("Expected: %v, got %v", expected, messages)
+	}
+}
+
+func TestLexUnterminatedComment(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("(\n/* never closed\n)"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.LeftParen, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 19, End: 19, Line: 3}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+	errs := em.Errors()
+	if len(errs) != 1 || errs[0].Code != erremitter.CodeUnterminatedComment || errs[0].ErrScope != (erremitter.ErrScope{Start: 2, End: 3}) {
+		t.Errorf("Expected an unterminated comment error at 2-3, got %v", errs)
+	}
+}
+
+func TestLexLineCommentAtEndOfFile(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("( // no newline"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.LeftParen, Scope: scope.Scope{Start: 0, End: 0, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 15, End: 15, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexRecordsFile(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	files := source.NewFileSet()
+	files.Add("a.rc", []rune("("))
+	l := lexer.NewFileLexer(files.Add("b.rc", []rune(")")), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.RightParen, Scope: scope.Scope{Start: 0, End: 0, Line: 1, File: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 1, End: 1, Line: 1, File: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) || len(em.Errors()) != 0 {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+}
+
+func TestLexNonASCIIDigits(t *testing.T) {
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune("12 ٣ x٣"), &em)
+	toks := l.Tokenize()
+	correctTokenSlice := []token.Token{
+		{Type: token.IntegerNumber, Scope: scope.Scope{Start: 0, End: 1, Line: 1}},
+		{Type: token.Illegal, Scope: scope.Scope{Start: 3, End: 3, Line: 1}},
+		{Type: token.Identifier, Scope: scope.Scope{Start: 5, End: 5, Line: 1}},
+		{Type: token.Illegal, Scope: scope.Scope{Start: 6, End: 6, Line: 1}},
+		{Type: token.Eof, Scope: scope.Scope{Start: 7, End: 7, Line: 1}},
+	}
+	if !slices.Equal(toks, correctTokenSlice) {
+		t.Errorf("Expected: %v, got %v", correctTokenSlice, toks)
+	}
+
+	var messages []string
+	for _, e := range em.Errors() {
+		messages = append(messages, e.Message)
+	}
+	expected := []string{"unexpected character '٣'", "unexpected character '٣'"}
+	if !slices.Equal(messages, expected) {
+		t.Errorf("Expected: %v, got %v", expected, messages)
+	}
+}
@@ -1,49 +1,149 @@
 package token
 
-import "github.com/Mixturka/rc/internal/pkg/scope"
+import (
+	"fmt"
+
+	"github.com/Mixturka/rc/internal/pkg/scope"
+)
 
 type TokenType int
 
 const (
-	LeftParen          TokenType = iota // (
-	RightParen                          // )
-	LeftBrace                           // {
-	RightBrace                          // }
-	Arrow                               // ->
-	Colon                               // :
-	Semicolon                           // ;
-	Comma                               // ,
-	Star                                // *
-	Minus                               // -
-	Plus                                // +
-	Slash                               // /
-	MinusAssign                         // -=
-	MinusMinus                          // --
-	PlusAssign                          // +=
-	PlusPlus                            // ++
-	StarAssign                          // *=
-	SlashAssign                         // /=
-	Assign                              // =
-	Equals                              // ==
-	NotEquals                           // !=
-	Not                                 // !
-	Tilde                               // ~
-	Percent                             // %
-	Ampersand                           // &
-	AmpersandAmpersand                  // &&
-	Bar                                 // |
-	BarBar                              // ||
-	Less                                // <
-	Greater                             // >
-	LessEqual                           // <=
-	GreaterEqual                        // >=
+	LeftParen            TokenType = iota // (
+	RightParen                            // )
+	LeftBrace                             // {
+	RightBrace                            // }
+	Arrow                                 // ->
+	Colon                                 // :
+	Semicolon                             // ;
+	Comma                                 // ,
+	Star                                  // *
+	Minus                                 // -
+	Plus                                  // +
+	Slash                                 // /
+	MinusAssign                           // -=
+	MinusMinus                            // --
+	PlusAssign                            // +=
+	PlusPlus                              // ++
+	StarAssign                            // *=
+	SlashAssign                           // /=
+	Assign                                // =
+	Equals                                // ==
+	NotEquals                             // !=
+	Not                                   // !
+	Tilde                                 // ~
+	Percent                               // %
+	Ampersand                             // &
+	AmpersandAmpersand                    // &&
+	Bar                                   // |
+	BarBar                                // ||
+	Less                                  // <
+	Greater                               // >
+	LessEqual                             // <=
+	GreaterEqual                          // >=
+	Caret                                 // ^
+	AmpersandAssign                       // &=
+	BarAssign                             // |=
+	CaretAssign                           // ^=
+	LessLess                              // <<
+	GreaterGreater                        // >>
+	LessLessAssign                        // <<=
+	GreaterGreaterAssign                  // >>=
+	DotDot                                // ..
+	DotDotEqual                           // ..=
 	Identifier
 	IntegerNumber
 	Fn
 	Return
+	Let
+	If
+	Else
+	While
+	Loop
+	Break
+	Continue
+	For
+	In
+	As
+	True
+	False
+	// Illegal is a run of characters that don't form a token. The lexer has
+	// already reported an error for it.
+	Illegal
 	Eof
 )
 
+var tokenTypeNames = [...]string{
+	LeftParen:            "LeftParen",
+	RightParen:           "RightParen",
+	LeftBrace:            "LeftBrace",
+	RightBrace:           "RightBrace",
+	Arrow:                "Arrow",
+	Colon:                "Colon",
+	Semicolon:            "Semicolon",
+	Comma:                "Comma",
+	Star:                 "Star",
+	Minus:                "Minus",
+	Plus:                 "Plus",
+	Slash:                "Slash",
+	MinusAssign:          "MinusAssign",
+	MinusMinus:           "MinusMinus",
+	PlusAssign:           "PlusAssign",
+	PlusPlus:             "PlusPlus",
+	StarAssign:           "StarAssign",
+	SlashAssign:          "SlashAssign",
+	Assign:               "Assign",
+	Equals:               "Equals",
+	NotEquals:            "NotEquals",
+	Not:                  "Not",
+	Tilde:                "Tilde",
+	Percent:              "Percent",
+	Ampersand:            "Ampersand",
+	AmpersandAmpersand:   "AmpersandAmpersand",
+	Bar:                  "Bar",
+	BarBar:               "BarBar",
+	Less:                 "Less",
+	Greater:              "Greater",
+	LessEqual:            "LessEqual",
+	GreaterEqual:         "GreaterEqual",
+	Caret:                "Caret",
+	AmpersandAssign:      "AmpersandAssign",
+	BarAssign:            "BarAssign",
+	CaretAssign:          "CaretAssign",
+	LessLess:             "LessLess",
+	GreaterGreater:       "GreaterGreater",
+	LessLessAssign:       "LessLessAssign",
+	GreaterGreaterAssign: "GreaterGreaterAssign",
+	DotDot:               "DotDot",
+	DotDotEqual:          "DotDotEqual",
+	Identifier:           "Identifier",
+	IntegerNumber:        "IntegerNumber",
+	Fn:                   "Fn",
+	Return:               "Return",
+	Let:                  "Let",
+	If:                   "If",
+	Else:                 "Else",
+	While:                "While",
+	Loop:                 "Loop",
+	Break:                "Break",
+	Continue:             "Continue",
+	For:                  "For",
+	In:                   "In",
+	As:                   "As",
+	True:                 "True",
+	False:                "False",
+	Illegal:              "Illegal",
+	Eof:                  "Eof",
+}
+
+func (tt TokenType) String() string {
+	if int(tt) < 0 || int(tt) >= len(tokenTypeNames) || tokenTypeNames[tt] == "" {
+		return fmt.Sprintf("TokenType(%d)", int(tt))
+	}
+
+	return tokenTypeNames[tt]
+}
+
 func (tt TokenType) IsOp() bool {
 	switch tt {
 	case Plus:
@@ -56,6 +156,8 @@ func (tt TokenType) IsOp() bool {
 		fallthrough
 	case MinusMinus:
 		fallthrough
+	case Not:
+		fallthrough
 	case Tilde:
 		fallthrough
 	case LeftParen:
@@ -80,7 +182,29 @@ func (tt TokenType) IsOp() bool {
 		fallthrough
 	case GreaterEqual:
 		fallthrough
+	case Equals:
+		fallthrough
+	case NotEquals:
+		fallthrough
 	case PlusPlus:
+		fallthrough
+	case Caret:
+		fallthrough
+	case LessLess:
+		fallthrough
+	case GreaterGreater:
+		fallthrough
+	case As:
+		return true
+	}
+
+	return tt.IsAssignOp()
+}
+
+func (tt TokenType) IsAssignOp() bool {
+	switch tt {
+	case Assign, PlusAssign, MinusAssign, StarAssign, SlashAssign,
+		AmpersandAssign, BarAssign, CaretAssign, LessLessAssign, GreaterGreaterAssign:
 		return true
 	}
 
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/types"
 )
 
 type ScopableNode interface {
@@ -12,8 +13,11 @@ type ScopableNode interface {
 	ScopeEnd() int
 }
 
+// PrintableNode is a node that can print itself as source-like text.
+// Operator expressions are printed in parentheses, so the text shows how
+// they grouped rather than how they were written.
 type PrintableNode interface {
-	Print(src string, sb *strings.Builder, nestingLevel int)
+	Print(src []rune, sb *strings.Builder, nestingLevel int)
 }
 
 type Node interface {
@@ -28,57 +32,299 @@ type Stmt interface {
 
 type Expr interface {
 	Node
+	// ExprType returns the type of the expression, filled in by semantic
+	// analysis.
+	ExprType() types.Type
+	SetType(typ types.Type)
+}
+
+// Typed is embedded in every expression to hold its type.
+type Typed struct {
+	Type types.Type
+}
+
+func (t *Typed) ExprType() types.Type {
+	return t.Type
+}
+
+func (t *Typed) SetType(typ types.Type) {
+	t.Type = typ
+}
+
+type TypeExpr interface {
+	Node
+}
+
+// Loop is a statement that 'break' and 'continue' can refer to.
+type Loop interface {
+	Stmt
+	// LoopLabel returns the label of the loop, or nil for an unlabeled one.
+	LoopLabel() *token.Token
+}
+
+// VarDecl is a declaration that introduces a variable, such as a 'let'
+// binding or a function parameter.
+type VarDecl interface {
+	Node
+	DeclName() token.Token
 }
 
 type Program struct {
-	Function Func
+	Functions []*Func
 }
 
 type Func struct {
+	Name       token.Token
+	Params     []*Param
+	ReturnType TypeExpr
+	Body       *BlockStmt
+}
+
+type Param struct {
+	Name token.Token
+	Type TypeExpr
+}
+
+// NamedType is a type spelled as a single identifier, e.g. 'i32'.
+type NamedType struct {
 	Name token.Token
-	Body Stmt
+
+	// Type is the named type, filled in by semantic analysis.
+	Type types.Type
+}
+
+type BlockStmt struct {
+	LBrace token.Token
+	Stmts  []Stmt
+	RBrace token.Token
+}
+
+// LetStmt declares a variable. Type is nil when the declaration has no
+// type annotation.
+type LetStmt struct {
+	Let       token.Token
+	Name      token.Token
+	Type      TypeExpr
+	Value     Expr
+	Semicolon token.Token
+
+	// VarType is the type of the variable, filled in by semantic analysis
+	// from the annotation or the initializer.
+	VarType types.Type
+}
+
+// IfStmt runs Then when Cond holds and Else otherwise. Else is nil, an
+// *IfStmt for 'else if', or a *BlockStmt.
+type IfStmt struct {
+	If   token.Token
+	Cond Expr
+	Then *BlockStmt
+	Else Stmt
+}
+
+type WhileStmt struct {
+	Label *token.Token
+	While token.Token
+	Cond  Expr
+	Body  *BlockStmt
+}
+
+// LoopStmt runs its body until it is left with 'break' or 'return'.
+type LoopStmt struct {
+	Label *token.Token
+	Loop  token.Token
+	Body  *BlockStmt
+}
+
+// ForStmt runs its body once for every value of Var in the range from
+// Start up to End. Range is '..', which excludes End, or '..=', which
+// includes it.
+type ForStmt struct {
+	Label *token.Token
+	For   token.Token
+	Var   token.Token
+	Start Expr
+	Range token.Token
+	End   Expr
+	Body  *BlockStmt
+
+	// VarType is the type of the loop variable, filled in by semantic
+	// analysis from the bounds of the range.
+	VarType types.Type
+}
+
+// BreakStmt leaves the innermost loop, or the loop named by Label.
+type BreakStmt struct {
+	Break     token.Token
+	Label     *token.Token
+	Semicolon token.Token
+
+	// Target is the loop being left, filled in by semantic analysis.
+	Target Loop
+}
+
+// ContinueStmt starts the next iteration of the innermost loop, or of the
+// loop named by Label.
+type ContinueStmt struct {
+	Continue  token.Token
+	Label     *token.Token
+	Semicolon token.Token
+
+	// Target is the loop being continued, filled in by semantic analysis.
+	Target Loop
+}
+
+// ExprStmt is an expression evaluated for its side effects.
+type ExprStmt struct {
+	Expr      Expr
+	Semicolon token.Token
 }
 
 type ReturnStmt struct {
-	Expr Expr
+	Return token.Token
+	Expr   Expr
 }
 
 type UnaryExpr struct {
+	Typed
 	Op  token.Token
 	Rhs Expr
 }
 
 type BinaryExpr struct {
+	Typed
 	Lhs Expr
 	Op  token.Token
 	Rhs Expr
 }
 
+// AssignExpr stores Value into Target. Op is '=' or one of the compound
+// assignment operators.
+type AssignExpr struct {
+	Typed
+	Target Expr
+	Op     token.Token
+	Value  Expr
+}
+
+type CallExpr struct {
+	Typed
+	Callee Expr
+	LParen token.Token
+	Args   []Expr
+	RParen token.Token
+
+	// Func is the called function, filled in by semantic analysis.
+	Func *Func
+}
+
+// VarExpr is a reference to a variable by name.
+type VarExpr struct {
+	Typed
+	Name token.Token
+
+	// Decl is the referenced declaration, filled in by semantic analysis.
+	Decl VarDecl
+}
+
+// IfExpr is the expression form of 'if'. Each branch is a braced
+// expression and the else branch is required. Else is an *IfExpr for
+// 'else if'. End is the '}' that closes the last branch.
+type IfExpr struct {
+	Typed
+	If   token.Token
+	Cond Expr
+	Then Expr
+	Else Expr
+	End  token.Token
+}
+
 type ConstExpr struct {
+	Typed
+	Value token.Token
+}
+
+// BoolExpr is one of the literals 'true' and 'false'.
+type BoolExpr struct {
+	Typed
 	Value token.Token
 }
 
+// IsTrue reports whether the literal is 'true'.
+func (bx *BoolExpr) IsTrue() bool {
+	return bx.Value.Type == token.True
+}
+
+// CastExpr converts Expr to the integer type Target, e.g. 'x as u8'.
+type CastExpr struct {
+	Typed
+	Expr   Expr
+	As     token.Token
+	Target TypeExpr
+}
+
+// BadExpr stands in for an expression, or a type, that failed to parse.
+// Tok is the token the error was reported at.
+type BadExpr struct {
+	Typed
+	Tok token.Token
+}
+
+// BadStmt stands in for a statement that failed to parse.
+type BadStmt struct {
+	Tok token.Token
+}
+
 func (p Program) Accept(emitter CodeEmitter) {
 	emitter.EmitProgram(p)
 }
 
+func (p *Program) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("program: {\n")
+
+	for _, fn := range p.Functions {
+		fn.Print(src, sb, nestingLevel+1)
+	}
+
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("}\n")
+}
+
 func (f Func) Accept(emitter CodeEmitter) {
 	emitter.EmitFunc(f)
 }
 
-func (f *Func) Print(src string, sb *strings.Builder, nestingLevel int) {
+func (f *Func) Print(src []rune, sb *strings.Builder, nestingLevel int) {
 	writeIndent(sb, nestingLevel)
 	sb.WriteString("func: {\n")
 
 	nestingLevel++
 	writeIndent(sb, nestingLevel)
-	fmt.Fprintf(sb, "Name: %s\n", src[f.Name.Scope.Start:f.Name.Scope.End+1])
+	fmt.Fprintf(sb, "Name: %s\n", string(src[f.Name.Scope.Start:f.Name.Scope.End+1]))
+
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("Params: (")
+	for i, param := range f.Params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		param.Print(src, sb, nestingLevel)
+	}
+	sb.WriteString(")\n")
+
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("Returns: ")
+	f.ReturnType.Print(src, sb, nestingLevel)
+	sb.WriteRune('\n')
 
 	writeIndent(sb, nestingLevel)
 	sb.WriteString("Body: {\n")
 	nestingLevel++
 
-	f.Body.Print(src, sb, nestingLevel)
+	for _, stmt := range f.Body.Stmts {
+		stmt.Print(src, sb, nestingLevel)
+	}
 	nestingLevel--
 
 	writeIndent(sb, nestingLevel)
@@ -94,14 +340,283 @@ func (f *Func) ScopeStart() int {
 }
 
 func (f *Func) ScopeEnd() int {
-	return f.Body.ScopeEnd() + 1 // +1 is for '}'
+	return f.Body.ScopeEnd()
+}
+
+func (pm Param) Accept(emitter CodeEmitter) {
+	emitter.EmitParam(pm)
+}
+
+func (pm *Param) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString(string(src[pm.Name.Scope.Start : pm.Name.Scope.End+1]))
+	sb.WriteString(": ")
+	pm.Type.Print(src, sb, nestingLevel)
+}
+
+func (pm *Param) ScopeStart() int {
+	return pm.Name.Scope.Start
+}
+
+func (pm *Param) ScopeEnd() int {
+	return pm.Type.ScopeEnd()
+}
+
+func (pm *Param) DeclName() token.Token {
+	return pm.Name
+}
+
+func (nt NamedType) Accept(emitter CodeEmitter) {
+	emitter.EmitNamedType(nt)
+}
+
+func (nt *NamedType) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString(string(src[nt.Name.Scope.Start : nt.Name.Scope.End+1]))
+}
+
+func (nt *NamedType) ScopeStart() int {
+	return nt.Name.Scope.Start
+}
+
+func (nt *NamedType) ScopeEnd() int {
+	return nt.Name.Scope.End
+}
+
+func (bs BlockStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitBlockStmt(bs)
+}
+
+func (bs *BlockStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("{\n")
+
+	for _, stmt := range bs.Stmts {
+		stmt.Print(src, sb, nestingLevel+1)
+	}
+
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("}\n")
+}
+
+func (bs *BlockStmt) ScopeStart() int {
+	return bs.LBrace.Scope.Start
+}
+
+func (bs *BlockStmt) ScopeEnd() int {
+	return bs.RBrace.Scope.End
+}
+
+func (ls LetStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitLetStmt(ls)
+}
+
+func (ls *LetStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("let ")
+	sb.WriteString(string(src[ls.Name.Scope.Start : ls.Name.Scope.End+1]))
+	if ls.Type != nil {
+		sb.WriteString(": ")
+		ls.Type.Print(src, sb, nestingLevel)
+	}
+	sb.WriteString(" = ")
+	ls.Value.Print(src, sb, nestingLevel)
+	sb.WriteString(";\n")
+}
+
+func (ls *LetStmt) ScopeStart() int {
+	return ls.Let.Scope.Start
+}
+
+func (ls *LetStmt) ScopeEnd() int {
+	return ls.Semicolon.Scope.End
+}
+
+func (ls *LetStmt) DeclName() token.Token {
+	return ls.Name
+}
+
+func (is IfStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitIfStmt(is)
+}
+
+func (is *IfStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("if ")
+	is.Cond.Print(src, sb, nestingLevel)
+	sb.WriteRune('\n')
+	is.Then.Print(src, sb, nestingLevel)
+	if is.Else != nil {
+		writeIndent(sb, nestingLevel)
+		sb.WriteString("else\n")
+		is.Else.Print(src, sb, nestingLevel)
+	}
+}
+
+func (is *IfStmt) ScopeStart() int {
+	return is.If.Scope.Start
+}
+
+func (is *IfStmt) ScopeEnd() int {
+	if is.Else != nil {
+		return is.Else.ScopeEnd()
+	}
+	return is.Then.ScopeEnd()
+}
+
+func (ws WhileStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitWhileStmt(ws)
+}
+
+func (ws *WhileStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	writeLabel(src, sb, ws.Label)
+	sb.WriteString("while ")
+	ws.Cond.Print(src, sb, nestingLevel)
+	sb.WriteRune('\n')
+	ws.Body.Print(src, sb, nestingLevel)
+}
+
+func (ws *WhileStmt) ScopeStart() int {
+	if ws.Label != nil {
+		return ws.Label.Scope.Start
+	}
+	return ws.While.Scope.Start
+}
+
+func (ws *WhileStmt) ScopeEnd() int {
+	return ws.Body.ScopeEnd()
+}
+
+func (ws *WhileStmt) LoopLabel() *token.Token {
+	return ws.Label
+}
+
+func (ls LoopStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitLoopStmt(ls)
+}
+
+func (ls *LoopStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	writeLabel(src, sb, ls.Label)
+	sb.WriteString("loop\n")
+	ls.Body.Print(src, sb, nestingLevel)
+}
+
+func (ls *LoopStmt) ScopeStart() int {
+	if ls.Label != nil {
+		return ls.Label.Scope.Start
+	}
+	return ls.Loop.Scope.Start
+}
+
+func (ls *LoopStmt) ScopeEnd() int {
+	return ls.Body.ScopeEnd()
+}
+
+func (ls *LoopStmt) LoopLabel() *token.Token {
+	return ls.Label
+}
+
+func (fs ForStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitForStmt(fs)
+}
+
+func (fs *ForStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	writeLabel(src, sb, fs.Label)
+	sb.WriteString("for ")
+	sb.WriteString(string(src[fs.Var.Scope.Start : fs.Var.Scope.End+1]))
+	sb.WriteString(" in ")
+	fs.Start.Print(src, sb, nestingLevel)
+	sb.WriteString(string(src[fs.Range.Scope.Start : fs.Range.Scope.End+1]))
+	fs.End.Print(src, sb, nestingLevel)
+	sb.WriteRune('\n')
+	fs.Body.Print(src, sb, nestingLevel)
+}
+
+func (fs *ForStmt) ScopeStart() int {
+	if fs.Label != nil {
+		return fs.Label.Scope.Start
+	}
+	return fs.For.Scope.Start
+}
+
+func (fs *ForStmt) ScopeEnd() int {
+	return fs.Body.ScopeEnd()
+}
+
+func (fs *ForStmt) LoopLabel() *token.Token {
+	return fs.Label
+}
+
+func (fs *ForStmt) DeclName() token.Token {
+	return fs.Var
+}
+
+func (fs *ForStmt) Inclusive() bool {
+	return fs.Range.Type == token.DotDotEqual
+}
+
+func (bs BreakStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitBreakStmt(bs)
+}
+
+func (bs *BreakStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("break")
+	writeJumpLabel(src, sb, bs.Label)
+	sb.WriteString(";\n")
+}
+
+func (bs *BreakStmt) ScopeStart() int {
+	return bs.Break.Scope.Start
+}
+
+func (bs *BreakStmt) ScopeEnd() int {
+	return bs.Semicolon.Scope.End
+}
+
+func (cs ContinueStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitContinueStmt(cs)
+}
+
+func (cs *ContinueStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("continue")
+	writeJumpLabel(src, sb, cs.Label)
+	sb.WriteString(";\n")
+}
+
+func (cs *ContinueStmt) ScopeStart() int {
+	return cs.Continue.Scope.Start
+}
+
+func (cs *ContinueStmt) ScopeEnd() int {
+	return cs.Semicolon.Scope.End
+}
+
+func (es ExprStmt) Accept(emitter CodeEmitter) {
+	emitter.EmitExprStmt(es)
+}
+
+func (es *ExprStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	es.Expr.Print(src, sb, nestingLevel)
+	sb.WriteString(";\n")
+}
+
+func (es *ExprStmt) ScopeStart() int {
+	return es.Expr.ScopeStart()
+}
+
+func (es *ExprStmt) ScopeEnd() int {
+	return es.Semicolon.Scope.End
 }
 
 func (rs ReturnStmt) Accept(emitter CodeEmitter) {
 	emitter.EmitReturnStmt(rs)
 }
 
-func (rs *ReturnStmt) Print(src string, sb *strings.Builder, nestingLevel int) {
+func (rs *ReturnStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
 	writeIndent(sb, nestingLevel)
 	sb.WriteString("return ")
 
@@ -110,7 +625,7 @@ func (rs *ReturnStmt) Print(src string, sb *strings.Builder, nestingLevel int) {
 }
 
 func (rs *ReturnStmt) ScopeStart() int {
-	return rs.Expr.ScopeStart()
+	return rs.Return.Scope.Start
 }
 
 func (rs *ReturnStmt) ScopeEnd() int {
@@ -121,9 +636,11 @@ func (ux UnaryExpr) Accept(emitter CodeEmitter) {
 	emitter.EmitUnaryExpr(ux)
 }
 
-func (ux *UnaryExpr) Print(src string, sb *strings.Builder, nestingLevel int) {
-	sb.WriteString(src[ux.Op.Scope.Start : ux.Op.Scope.End+1])
+func (ux *UnaryExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteRune('(')
+	sb.WriteString(string(src[ux.Op.Scope.Start : ux.Op.Scope.End+1]))
 	ux.Rhs.Print(src, sb, nestingLevel)
+	sb.WriteRune(')')
 }
 
 func (ux *UnaryExpr) ScopeStart() int {
@@ -138,10 +655,12 @@ func (bx BinaryExpr) Accept(emitter CodeEmitter) {
 	emitter.EmitBinaryExpr(bx)
 }
 
-func (bx *BinaryExpr) Print(src string, sb *strings.Builder, nestingLevel int) {
+func (bx *BinaryExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteRune('(')
 	bx.Lhs.Print(src, sb, nestingLevel)
-	sb.WriteString(src[bx.Op.Scope.Start : bx.Op.Scope.End+1])
+	sb.WriteString(" " + string(src[bx.Op.Scope.Start:bx.Op.Scope.End+1]) + " ")
 	bx.Rhs.Print(src, sb, nestingLevel)
+	sb.WriteRune(')')
 }
 
 func (bx *BinaryExpr) ScopeStart() int {
@@ -152,12 +671,94 @@ func (bx *BinaryExpr) ScopeEnd() int {
 	return bx.Rhs.ScopeEnd()
 }
 
+func (ax AssignExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitAssignExpr(ax)
+}
+
+func (ax *AssignExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteRune('(')
+	ax.Target.Print(src, sb, nestingLevel)
+	sb.WriteString(" " + string(src[ax.Op.Scope.Start:ax.Op.Scope.End+1]) + " ")
+	ax.Value.Print(src, sb, nestingLevel)
+	sb.WriteRune(')')
+}
+
+func (ax *AssignExpr) ScopeStart() int {
+	return ax.Target.ScopeStart()
+}
+
+func (ax *AssignExpr) ScopeEnd() int {
+	return ax.Value.ScopeEnd()
+}
+
+func (cx CallExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitCallExpr(cx)
+}
+
+func (cx *CallExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	cx.Callee.Print(src, sb, nestingLevel)
+	sb.WriteRune('(')
+	for i, arg := range cx.Args {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		arg.Print(src, sb, nestingLevel)
+	}
+	sb.WriteRune(')')
+}
+
+func (cx *CallExpr) ScopeStart() int {
+	return cx.Callee.ScopeStart()
+}
+
+func (cx *CallExpr) ScopeEnd() int {
+	return cx.RParen.Scope.End
+}
+
+func (vx VarExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitVarExpr(vx)
+}
+
+func (vx *VarExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString(string(src[vx.Name.Scope.Start : vx.Name.Scope.End+1]))
+}
+
+func (vx *VarExpr) ScopeStart() int {
+	return vx.Name.Scope.Start
+}
+
+func (vx *VarExpr) ScopeEnd() int {
+	return vx.Name.Scope.End
+}
+
+func (ix IfExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitIfExpr(ix)
+}
+
+func (ix *IfExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString("(if ")
+	ix.Cond.Print(src, sb, nestingLevel)
+	sb.WriteString(" { ")
+	ix.Then.Print(src, sb, nestingLevel)
+	sb.WriteString(" } else { ")
+	ix.Else.Print(src, sb, nestingLevel)
+	sb.WriteString(" })")
+}
+
+func (ix *IfExpr) ScopeStart() int {
+	return ix.If.Scope.Start
+}
+
+func (ix *IfExpr) ScopeEnd() int {
+	return ix.End.Scope.End
+}
+
 func (ce ConstExpr) Accept(emitter CodeEmitter) {
 	emitter.EmitConstExpr(ce)
 }
 
-func (ce *ConstExpr) Print(src string, sb *strings.Builder, nestingLevel int) {
-	sb.WriteString(src[ce.Value.Scope.Start : ce.Value.Scope.End+1])
+func (ce *ConstExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString(string(src[ce.Value.Scope.Start : ce.Value.Scope.End+1]))
 }
 
 func (ce *ConstExpr) ScopeStart() int {
@@ -168,6 +769,87 @@ func (ce *ConstExpr) ScopeEnd() int {
 	return ce.Value.Scope.End
 }
 
+func (bx BoolExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitBoolExpr(bx)
+}
+
+func (bx *BoolExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString(string(src[bx.Value.Scope.Start : bx.Value.Scope.End+1]))
+}
+
+func (bx *BoolExpr) ScopeStart() int {
+	return bx.Value.Scope.Start
+}
+
+func (bx *BoolExpr) ScopeEnd() int {
+	return bx.Value.Scope.End
+}
+
+func (cx CastExpr) Accept(emitter CodeEmitter) {
+	emitter.EmitCastExpr(cx)
+}
+
+func (cx *CastExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteRune('(')
+	cx.Expr.Print(src, sb, nestingLevel)
+	sb.WriteString(" as ")
+	cx.Target.Print(src, sb, nestingLevel)
+	sb.WriteRune(')')
+}
+
+func (cx *CastExpr) ScopeStart() int {
+	return cx.Expr.ScopeStart()
+}
+
+func (cx *CastExpr) ScopeEnd() int {
+	return cx.Target.ScopeEnd()
+}
+
+// Bad nodes only exist in programs with syntax errors, which never reach
+// code generation, so there is nothing to emit for them.
+func (bx BadExpr) Accept(emitter CodeEmitter) {}
+
+func (bx *BadExpr) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	sb.WriteString("<bad expression>")
+}
+
+func (bx *BadExpr) ScopeStart() int {
+	return bx.Tok.Scope.Start
+}
+
+func (bx *BadExpr) ScopeEnd() int {
+	return bx.Tok.Scope.End
+}
+
+func (bs BadStmt) Accept(emitter CodeEmitter) {}
+
+func (bs *BadStmt) Print(src []rune, sb *strings.Builder, nestingLevel int) {
+	writeIndent(sb, nestingLevel)
+	sb.WriteString("<bad statement>\n")
+}
+
+func (bs *BadStmt) ScopeStart() int {
+	return bs.Tok.Scope.Start
+}
+
+func (bs *BadStmt) ScopeEnd() int {
+	return bs.Tok.Scope.End
+}
+
+func writeLabel(src []rune, sb *strings.Builder, label *token.Token) {
+	if label != nil {
+		sb.WriteString(string(src[label.Scope.Start : label.Scope.End+1]))
+		sb.WriteString(": ")
+	}
+}
+
+func writeJumpLabel(src []rune, sb *strings.Builder, label *token.Token) {
+	if label != nil {
+		sb.WriteRune(' ')
+		sb.WriteString(string(src[label.Scope.Start : label.Scope.End+1]))
+	}
+}
+
 func writeIndent(sb *strings.Builder, nestingLevel int) {
 	for range nestingLevel {
 		sb.WriteString("  ")
@@ -177,8 +859,25 @@ func writeIndent(sb *strings.Builder, nestingLevel int) {
 type CodeEmitter interface {
 	EmitProgram(program Program)
 	EmitFunc(fn Func)
+	EmitParam(param Param)
+	EmitNamedType(typ NamedType)
 	EmitUnaryExpr(expr UnaryExpr)
 	EmitBinaryExpr(expr BinaryExpr)
+	EmitBlockStmt(stmt BlockStmt)
+	EmitLetStmt(stmt LetStmt)
+	EmitIfStmt(stmt IfStmt)
+	EmitWhileStmt(stmt WhileStmt)
+	EmitLoopStmt(stmt LoopStmt)
+	EmitForStmt(stmt ForStmt)
+	EmitBreakStmt(stmt BreakStmt)
+	EmitContinueStmt(stmt ContinueStmt)
+	EmitExprStmt(stmt ExprStmt)
 	EmitReturnStmt(stmt ReturnStmt)
+	EmitAssignExpr(expr AssignExpr)
+	EmitCallExpr(expr CallExpr)
+	EmitVarExpr(expr VarExpr)
+	EmitIfExpr(expr IfExpr)
 	EmitConstExpr(expr ConstExpr)
+	EmitBoolExpr(expr BoolExpr)
+	EmitCastExpr(expr CastExpr)
 }
@@ -1,15 +1,22 @@
 package parser
 
 import (
-	"fmt"
-	"log"
+	"errors"
 
 	"github.com/Mixturka/rc/internal/erremitter"
 	"github.com/Mixturka/rc/internal/lexer/token"
 	"github.com/Mixturka/rc/internal/parser/ast"
 )
 
+// Sync sets hold the tokens the parser can resume at after a syntax error.
+// Every parsing function pushes the set for the construct it parses, and
+// recovery skips tokens until it finds one from the innermost set. Tokens
+// from the enclosing sets stop it too, so a broken expression can't swallow
+// the statements after it.
 var (
+	programSyncSet = map[token.TokenType]struct{}{
+		token.Fn: {},
+	}
 	funcSignSyncSet = map[token.TokenType]struct{}{
 		token.LeftBrace:  {},
 		token.RightBrace: {},
@@ -19,7 +26,16 @@ var (
 	}
 	stmtSyncSet = map[token.TokenType]struct{}{
 		token.Semicolon:  {},
+		token.LeftBrace:  {},
 		token.RightBrace: {},
+		token.Let:        {},
+		token.Return:     {},
+		token.If:         {},
+		token.While:      {},
+		token.Loop:       {},
+		token.For:        {},
+		token.Break:      {},
+		token.Continue:   {},
 	}
 	exprSyncSet = map[token.TokenType]struct{}{
 		token.Comma:      {},
@@ -28,6 +44,9 @@ var (
 )
 
 type Parser struct {
+	// inErr is set while recovering from a syntax error, and cleared once a
+	// token is consumed again. Errors found in the meantime are most likely
+	// caused by the first one, so they aren't reported.
 	inErr            bool
 	pos              int
 	tokens           []token.Token
@@ -45,21 +64,27 @@ func NewParser(tokens []token.Token, errEmitter *erremitter.ErrEmitter, src []ru
 	}
 }
 
+// next consumes and returns the next token. The final Eof token is never
+// consumed, it is returned on every call once reached.
 func (p *Parser) next() *token.Token {
-	if p.pos >= len(p.tokens) {
-		return nil
+	tok := p.tokens[p.pos]
+	if tok.Type != token.Eof {
+		p.pos++
 	}
-
-	token := p.tokens[p.pos]
-	p.pos++
-	return &token
+	p.inErr = false
+	return &tok
 }
 
-func (p *Parser) peek() *token.Token {
-	if p.pos >= len(p.tokens) {
-		return nil
+// peekAfter returns the token following the one returned by peek.
+func (p *Parser) peekAfter() *token.Token {
+	if p.pos+1 >= len(p.tokens) {
+		return &p.tokens[len(p.tokens)-1]
 	}
 
+	return &p.tokens[p.pos+1]
+}
+
+func (p *Parser) peek() *token.Token {
 	return &p.tokens[p.pos]
 }
 
@@ -68,67 +93,233 @@ func (p *Parser) Parse() *ast.Program {
 }
 
 func (p *Parser) parseProgram() *ast.Program {
-	fn := p.ParseFunction()
-	return &ast.Program{Function: *fn}
+	p.pushSyncStack(programSyncSet)
+	defer p.popSyncStack()
+
+	program := &ast.Program{}
+	for p.peek().Type != token.Eof {
+		start := p.pos
+		program.Functions = append(program.Functions, p.ParseFunction())
+		p.skipIfStuck(start)
+	}
+
+	return program
 }
 
 func (p *Parser) ParseFunction() *ast.Func {
 	p.pushSyncStack(funcSignSyncSet)
-	defer p.popSyncStack()
 
-	if _, ok := p.expectAndConsumeToken(token.Fn); !ok {
-		log.Fatalf("expected 'fn' keyword")
+	p.expect(token.Fn, "expected 'fn' keyword")
+	funcName, _ := p.expect(token.Identifier, "expected function name")
+
+	p.expect(token.LeftParen, "expected '('")
+	params := p.parseParams()
+	p.expect(token.RightParen, "expected ')'")
+	p.expect(token.Arrow, "expected '->'")
+	returnType := p.parseType()
+
+	p.popSyncStack()
+	body := p.parseBlock()
+
+	return &ast.Func{
+		Name:       funcName,
+		Params:     params,
+		ReturnType: returnType,
+		Body:       body,
 	}
-	var funcName token.Token
-	var ok bool
-	funcName, ok = p.expectAndConsumeToken(token.Identifier)
-	if !ok {
-		log.Fatalf("expected function name")
+}
+
+// parseParams parses a possibly empty, comma separated parameter list up to,
+// but not including, the closing ')'. A trailing comma is allowed.
+func (p *Parser) parseParams() []*ast.Param {
+	var params []*ast.Param
+	for p.peek().Type != token.RightParen && p.peek().Type != token.Eof {
+		name, ok := p.expect(token.Identifier, "expected parameter name")
+		if !ok {
+			break
+		}
+		p.expect(token.Colon, "expected ':' after parameter name")
+		params = append(params, &ast.Param{Name: name, Type: p.parseType()})
+
+		if _, ok := p.expectAndConsumeToken(token.Comma); !ok {
+			break
+		}
 	}
 
-	if _, ok := p.expectAndConsumeToken(token.LeftParen); !ok {
-		log.Fatalf("expected '('")
+	return params
+}
+
+func (p *Parser) parseType() ast.TypeExpr {
+	at := *p.peek()
+	name, ok := p.expect(token.Identifier, "expected type name")
+	if !ok {
+		return &ast.BadExpr{Tok: at}
 	}
-	if _, ok := p.expectAndConsumeToken(token.RightParen); !ok {
-		log.Fatalf("expected ')'")
+
+	return &ast.NamedType{Name: name}
+}
+
+func (p *Parser) parseBlock() *ast.BlockStmt {
+	lBrace, ok := p.expect(token.LeftBrace, "expected '{'")
+	if !ok {
+		return &ast.BlockStmt{LBrace: *p.peek(), RBrace: *p.peek()}
 	}
-	if _, ok := p.expectAndConsumeToken(token.Arrow); !ok {
-		log.Fatalf("expected '->'")
+
+	var stmts []ast.Stmt
+	// 'fn' can't start a statement. Seeing one means the '}' is missing and
+	// the next function has begun.
+	for p.peek().Type != token.RightBrace && p.peek().Type != token.Fn && p.peek().Type != token.Eof {
+		start := p.pos
+		stmts = append(stmts, p.parseStatement())
+		p.skipIfStuck(start)
 	}
-	if _, ok := p.expectAndConsumeToken(token.Identifier); !ok {
-		log.Fatalf("expected return type")
+
+	rBrace, _ := p.expect(token.RightBrace, "expected '}'")
+	return &ast.BlockStmt{LBrace: lBrace, Stmts: stmts, RBrace: rBrace}
+}
+
+func (p *Parser) parseStatement() ast.Stmt {
+	switch p.peek().Type {
+	case token.LeftBrace:
+		return p.parseBlock()
+	case token.Return:
+		return p.parseReturn()
+	case token.Let:
+		return p.parseLet()
+	case token.If:
+		return p.parseIf()
+	case token.While, token.Loop, token.For:
+		return p.parseLoop(nil)
+	case token.Break, token.Continue:
+		return p.parseJump()
+	case token.Identifier:
+		if p.peekAfter().Type == token.Colon {
+			label := p.next()
+			p.next()
+			return p.parseLoop(label)
+		}
 	}
-	if _, ok := p.expectAndConsumeToken(token.LeftBrace); !ok {
-		log.Fatalf("expected '{' before function body")
+
+	return p.parseExprStmt()
+}
+
+func (p *Parser) parseIf() ast.Stmt {
+	ifTok := *p.next()
+
+	cond := p.parseExpression(0)
+	stmt := &ast.IfStmt{If: ifTok, Cond: cond, Then: p.parseBlock()}
+
+	if _, ok := p.expectAndConsumeToken(token.Else); ok {
+		if p.peek().Type == token.If {
+			stmt.Else = p.parseIf()
+		} else {
+			stmt.Else = p.parseBlock()
+		}
 	}
 
-	stmt := p.parseStatement()
+	return stmt
+}
 
-	if _, ok := p.expectAndConsumeToken(token.RightBrace); !ok {
-		log.Fatalf("expected '}'")
+// parseLoop parses a loop statement. label is the already consumed label
+// in front of it, if any.
+func (p *Parser) parseLoop(label *token.Token) ast.Stmt {
+	tok := p.next()
+	switch tok.Type {
+	case token.While:
+		cond := p.parseExpression(0)
+		return &ast.WhileStmt{Label: label, While: *tok, Cond: cond, Body: p.parseBlock()}
+	case token.Loop:
+		return &ast.LoopStmt{Label: label, Loop: *tok, Body: p.parseBlock()}
+	case token.For:
+		return p.parseFor(label, *tok)
 	}
 
-	return &ast.Func{
-		Name: funcName,
-		Body: stmt,
+	p.syntaxErrAt("expected a loop after label", *tok)
+	return &ast.BadStmt{Tok: *tok}
+}
+
+func (p *Parser) parseFor(label *token.Token, forTok token.Token) ast.Stmt {
+	variable, _ := p.expect(token.Identifier, "expected loop variable after 'for'")
+	p.expect(token.In, "expected 'in' after loop variable")
+
+	start := p.parseExpression(0)
+	rng := *p.peek()
+	if rng.Type == token.DotDot || rng.Type == token.DotDotEqual {
+		p.next()
+	} else {
+		p.syntaxErr("expected '..' or '..=' in range")
+	}
+	end := p.parseExpression(0)
+
+	return &ast.ForStmt{
+		Label: label,
+		For:   forTok,
+		Var:   variable,
+		Start: start,
+		Range: rng,
+		End:   end,
+		Body:  p.parseBlock(),
 	}
 }
 
-func (p *Parser) parseStatement() ast.Stmt {
+// parseJump parses 'break' and 'continue' along with their optional label.
+func (p *Parser) parseJump() ast.Stmt {
 	p.pushSyncStack(stmtSyncSet)
 	defer p.popSyncStack()
 
-	if _, ok := p.expectAndConsumeToken(token.Return); !ok {
-		log.Fatalf("expected 'return'")
+	keyword := p.next()
+
+	var label *token.Token
+	if tok, ok := p.expectAndConsumeToken(token.Identifier); ok {
+		label = &tok
 	}
 
+	semicolon, _ := p.expect(token.Semicolon, "expected ';' in the end of statement")
+
+	if keyword.Type == token.Break {
+		return &ast.BreakStmt{Break: *keyword, Label: label, Semicolon: semicolon}
+	}
+	return &ast.ContinueStmt{Continue: *keyword, Label: label, Semicolon: semicolon}
+}
+
+func (p *Parser) parseExprStmt() ast.Stmt {
+	p.pushSyncStack(stmtSyncSet)
+	defer p.popSyncStack()
+
+	expr := p.parseExpression(0)
+	semicolon, _ := p.expect(token.Semicolon, "expected ';' in the end of statement")
+
+	return &ast.ExprStmt{Expr: expr, Semicolon: semicolon}
+}
+
+func (p *Parser) parseReturn() ast.Stmt {
+	p.pushSyncStack(stmtSyncSet)
+	defer p.popSyncStack()
+
+	ret := *p.next()
 	expr := p.parseExpression(0)
+	p.expect(token.Semicolon, "expected ';' in the end of statement")
+
+	return &ast.ReturnStmt{Return: ret, Expr: expr}
+}
+
+func (p *Parser) parseLet() ast.Stmt {
+	p.pushSyncStack(stmtSyncSet)
+	defer p.popSyncStack()
+
+	let := *p.next()
+	name, _ := p.expect(token.Identifier, "expected variable name after 'let'")
 
-	if _, ok := p.expectAndConsumeToken(token.Semicolon); !ok {
-		log.Fatalf("expected ';' in the end of statement")
+	var typ ast.TypeExpr
+	if _, ok := p.expectAndConsumeToken(token.Colon); ok {
+		typ = p.parseType()
 	}
 
-	return &ast.ReturnStmt{Expr: expr}
+	p.expect(token.Assign, "expected '=' in variable declaration")
+	value := p.parseExpression(0)
+	semicolon, _ := p.expect(token.Semicolon, "expected ';' in the end of statement")
+
+	return &ast.LetStmt{Let: let, Name: name, Type: typ, Value: value, Semicolon: semicolon}
 }
 
 // minBp - minimal BindingPower for Pratt's Parser loop
@@ -136,15 +327,32 @@ func (p *Parser) parseExpression(minBp uint8) ast.Expr {
 	p.pushSyncStack(exprSyncSet)
 	defer p.popSyncStack()
 
-	tok := p.next()
-	fmt.Printf("Cur expr token: %v\n", tok)
-	var lhs ast.Expr = &ast.ConstExpr{Value: *tok}
-	if tok.Type.IsOp() {
+	tok := p.peek()
+	if !startsExpression(tok.Type) {
+		p.syntaxErr("expected expression")
+		return &ast.BadExpr{Tok: *tok}
+	}
+	tok = p.next()
+
+	var lhs ast.Expr
+	switch {
+	case tok.Type == token.Identifier:
+		lhs = &ast.VarExpr{Name: *tok}
+	case tok.Type == token.IntegerNumber:
+		lhs = &ast.ConstExpr{Value: *tok}
+	case tok.Type == token.True || tok.Type == token.False:
+		lhs = &ast.BoolExpr{Value: *tok}
+	case tok.Type == token.If:
+		lhs = p.parseIfExpr(*tok)
+	case tok.Type == token.PlusPlus || tok.Type == token.MinusMinus:
+		p.reportIncDec(*tok, true)
+		_, rBp := prefixBindingPower(tok.Type)
+		rhs := p.parseExpression(rBp)
+		lhs = &ast.UnaryExpr{Op: *tok, Rhs: rhs}
+	case tok.Type.IsOp():
 		if tok.Type == token.LeftParen {
 			lhs = p.parseExpression(0)
-			if p.next().Type != token.RightParen {
-				log.Fatal("expected ')' after expression")
-			}
+			p.expect(token.RightParen, "expected ')' after expression")
 		} else {
 			_, rBp := prefixBindingPower(tok.Type)
 			rhs := p.parseExpression(rBp)
@@ -159,6 +367,25 @@ func (p *Parser) parseExpression(minBp uint8) ast.Expr {
 		}
 		// TODO add EOF check here
 
+		if tok.Type == token.PlusPlus || tok.Type == token.MinusMinus {
+			p.next()
+			p.reportIncDec(*tok, false)
+			continue
+		}
+
+		if lBp, ok := postfixBindingPower(tok.Type); ok {
+			if lBp < minBp {
+				break
+			}
+
+			if tok.Type == token.As {
+				lhs = &ast.CastExpr{Expr: lhs, As: *p.next(), Target: p.parseType()}
+			} else {
+				lhs = p.parseCall(lhs)
+			}
+			continue
+		}
+
 		lBp, rBp, ok := infixBindingPower(tok.Type)
 		if !ok {
 			break
@@ -167,14 +394,151 @@ func (p *Parser) parseExpression(minBp uint8) ast.Expr {
 			break
 		}
 
-		p.next()
+		op := *p.next()
 		rhs := p.parseExpression(rBp)
-		lhs = &ast.BinaryExpr{Lhs: lhs, Op: *tok, Rhs: rhs}
+		if op.Type.IsAssignOp() {
+			lhs = &ast.AssignExpr{Target: lhs, Op: op, Value: rhs}
+		} else {
+			lhs = &ast.BinaryExpr{Lhs: lhs, Op: op, Rhs: rhs}
+		}
 	}
 
 	return lhs
 }
 
+// parseIfExpr parses the rest of an 'if' expression whose 'if' keyword has
+// already been consumed.
+func (p *Parser) parseIfExpr(ifTok token.Token) *ast.IfExpr {
+	cond := p.parseExpression(0)
+	then, end := p.parseBracedExpr()
+
+	if _, ok := p.expect(token.Else, "expected 'else', an 'if' expression must have a value in both branches"); !ok {
+		return &ast.IfExpr{If: ifTok, Cond: cond, Then: then, Else: &ast.BadExpr{Tok: end}, End: end}
+	}
+
+	if elseIf, ok := p.expectAndConsumeToken(token.If); ok {
+		els := p.parseIfExpr(elseIf)
+		return &ast.IfExpr{If: ifTok, Cond: cond, Then: then, Else: els, End: els.End}
+	}
+
+	els, end := p.parseBracedExpr()
+	return &ast.IfExpr{If: ifTok, Cond: cond, Then: then, Else: els, End: end}
+}
+
+// parseBracedExpr parses a branch of an 'if' expression, '{' expr '}', and
+// returns the expression along with the closing brace.
+func (p *Parser) parseBracedExpr() (ast.Expr, token.Token) {
+	if _, ok := p.expect(token.LeftBrace, "expected '{'"); !ok {
+		return &ast.BadExpr{Tok: *p.peek()}, *p.peek()
+	}
+
+	expr := p.parseExpression(0)
+	rBrace, _ := p.expect(token.RightBrace, "expected '}' after the value of an 'if' branch")
+
+	return expr, rBrace
+}
+
+// reportIncDec rejects '++' and '--'. C gives them an evaluation order that
+// is easy to get wrong, so rc leaves them out in favour of '+= 1' and '-= 1'.
+func (p *Parser) reportIncDec(op token.Token, prefix bool) {
+	var message string
+	switch {
+	case op.Type == token.PlusPlus:
+		message = "rc has no increment operator '++', use '+= 1' instead"
+	case prefix:
+		message = "rc has no decrement operator '--', use '-= 1' instead, or '- -' to negate twice"
+	default:
+		message = "rc has no decrement operator '--', use '-= 1' instead"
+	}
+
+	p.reportErr(erremitter.CodeIncDec, message, op)
+}
+
+// reportErr reports an error at tok. Once the error limit is reached, the
+// rest of the input is skipped.
+func (p *Parser) reportErr(code erremitter.Code, message string, tok token.Token) {
+	err := p.errEmitter.Add(erremitter.Err{
+		Severity:  erremitter.Error,
+		Code:      code,
+		Message:   message,
+		ErrScope:  erremitter.ErrScope{File: tok.Scope.File, Start: tok.Scope.Start, End: tok.Scope.End},
+		Squiggles: []erremitter.SquiggleScope{{File: tok.Scope.File, Start: tok.Scope.Start, End: tok.Scope.End, Lines: 1}},
+	})
+	if errors.Is(err, erremitter.ErrMaxReached) {
+		p.pos = len(p.tokens) - 1
+	}
+}
+
+// syntaxErr reports a syntax error at the next token and skips ahead to a
+// token the parser can resume at. Errors are suppressed while recovering
+// from a previous one.
+func (p *Parser) syntaxErr(message string) {
+	p.syntaxErrAt(message, *p.peek())
+}
+
+func (p *Parser) syntaxErrAt(message string, tok token.Token) {
+	// Illegal tokens were already reported by the lexer.
+	if !p.inErr && tok.Type != token.Illegal {
+		p.reportErr(erremitter.CodeSyntax, message, tok)
+	}
+	p.inErr = true
+	p.synchronize()
+}
+
+// synchronize skips tokens until one of the sync sets on the stack, the
+// innermost checked first, contains the next token.
+func (p *Parser) synchronize() {
+	for p.peek().Type != token.Eof {
+		for i := len(p.currentSyncStack) - 1; i >= 0; i-- {
+			if _, ok := p.currentSyncStack[i][p.peek().Type]; ok {
+				return
+			}
+		}
+		p.pos++
+	}
+}
+
+// skipIfStuck skips a token if nothing was consumed since start, so that a
+// loop over a list of items keeps making progress past a broken one.
+func (p *Parser) skipIfStuck(start int) {
+	if p.pos == start && p.peek().Type != token.Eof {
+		p.pos++
+	}
+}
+
+// expect consumes the next token if it has type tt. Otherwise it reports a
+// syntax error with message and recovers. If recovery stops right at a
+// token of type tt, that token is consumed and parsing goes on as if it
+// was found in the first place.
+func (p *Parser) expect(tt token.TokenType, message string) (token.Token, bool) {
+	if tok, ok := p.expectAndConsumeToken(tt); ok {
+		return tok, true
+	}
+
+	p.syntaxErr(message)
+	if tok, ok := p.expectAndConsumeToken(tt); ok {
+		return tok, true
+	}
+	return *p.peek(), false
+}
+
+func (p *Parser) parseCall(callee ast.Expr) ast.Expr {
+	lParen := p.next()
+
+	var args []ast.Expr
+	for p.peek().Type != token.RightParen && p.peek().Type != token.Eof {
+		args = append(args, p.parseExpression(0))
+
+		if _, ok := p.expectAndConsumeToken(token.Comma); !ok {
+			break
+		}
+	}
+
+	rParen, _ := p.expect(token.RightParen, "expected ')' after call arguments")
+
+	return &ast.CallExpr{Callee: callee, LParen: *lParen, Args: args, RParen: rParen}
+}
+
 func (p *Parser) expectAndConsumeToken(tok token.TokenType) (token.Token, bool) {
 	if p.peek().Type == token.Eof {
 		return token.Token{}, false
@@ -198,29 +562,82 @@ func (p *Parser) popSyncStack() {
 	}
 }
 
+// startsExpression reports whether a token of type tt can begin an
+// expression.
+func startsExpression(tt token.TokenType) bool {
+	switch tt {
+	case token.Identifier, token.IntegerNumber, token.True, token.False, token.If, token.LeftParen:
+		return true
+	}
+
+	_, rBp := prefixBindingPower(tt)
+	return rBp != 0
+}
+
 func prefixBindingPower(op token.TokenType) (struct{}, uint8) {
 	switch op {
+	case token.Not:
+		fallthrough
 	case token.Tilde:
 		fallthrough
 	case token.Plus:
 		fallthrough
 	case token.Minus:
-		return struct{}{}, 13
+		fallthrough
+	// Not operators in rc, but their operand is still parsed so that the
+	// error about them doesn't cascade.
+	case token.PlusPlus:
+		fallthrough
+	case token.MinusMinus:
+		return struct{}{}, 24
 	}
 
 	return struct{}{}, 0
 }
 
+func postfixBindingPower(op token.TokenType) (uint8, bool) {
+	switch op {
+	case token.LeftParen:
+		return 25, true
+	// Looser than prefix operators, so '-x as u8' converts '-x'.
+	case token.As:
+		return 23, true
+	}
+
+	return 0, false
+}
+
 func infixBindingPower(op token.TokenType) (uint8, uint8, bool) {
 	switch op {
+	case token.Assign:
+		fallthrough
+	case token.PlusAssign:
+		fallthrough
+	case token.MinusAssign:
+		fallthrough
+	case token.StarAssign:
+		fallthrough
+	case token.SlashAssign:
+		fallthrough
+	case token.AmpersandAssign:
+		fallthrough
+	case token.BarAssign:
+		fallthrough
+	case token.CaretAssign:
+		fallthrough
+	case token.LessLessAssign:
+		fallthrough
+	case token.GreaterGreaterAssign:
+		// Right-associative, so a = b = c assigns c to b first.
+		return 2, 1, true
 	case token.BarBar:
-		return 1, 2, true
-	case token.AmpersandAmpersand:
 		return 3, 4, true
+	case token.AmpersandAmpersand:
+		return 5, 6, true
 	case token.NotEquals:
 		fallthrough
 	case token.Equals:
-		return 5, 6, true
+		return 7, 8, true
 	case token.GreaterEqual:
 		fallthrough
 	case token.LessEqual:
@@ -228,17 +645,30 @@ func infixBindingPower(op token.TokenType) (uint8, uint8, bool) {
 	case token.Greater:
 		fallthrough
 	case token.Less:
-		return 7, 8, true
+		return 9, 10, true
+	// Unlike in C, the bitwise operators bind tighter than comparisons, so
+	// 'x & 1 == 0' tests the lowest bit of x. Shifts sit between them and
+	// the additive operators, as they do in C.
+	case token.Bar:
+		return 11, 12, true
+	case token.Caret:
+		return 13, 14, true
+	case token.Ampersand:
+		return 15, 16, true
+	case token.LessLess:
+		fallthrough
+	case token.GreaterGreater:
+		return 17, 18, true
 	case token.Plus:
 		fallthrough
 	case token.Minus:
-		return 9, 10, true
+		return 19, 20, true
 	case token.Slash:
 		fallthrough
 	case token.Percent:
 		fallthrough
 	case token.Star:
-		return 11, 12, true
+		return 21, 22, true
 	}
 
 	return 0, 0, false
@@ -0,0 +1,269 @@
+package parser_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer"
+	"github.com/Mixturka/rc/internal/parser"
+	"github.com/Mixturka/rc/internal/parser/ast"
+)
+
+func parse(t *testing.T, src string) (*ast.Program, []string) {
+	t.Helper()
+
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(src), &em)
+	toks := l.Tokenize()
+	p := parser.NewParser(toks, &em, []rune(src))
+	program := p.Parse()
+
+	var messages []string
+	for _, e := range em.Errors() {
+		messages = append(messages, e.Message)
+	}
+	return program, messages
+}
+
+// parseExpr parses src as the returned expression of a function and
+// prints it back fully parenthesized.
+func parseExpr(t *testing.T, src string) (string, []string) {
+	t.Helper()
+
+	fnSrc := "fn main() -> i32 { return " + src + "; }"
+	program, errs := parse(t, fnSrc)
+	ret := program.Functions[0].Body.Stmts[0].(*ast.ReturnStmt)
+
+	var sb strings.Builder
+	ret.Expr.Print([]rune(fnSrc), &sb, 0)
+	return sb.String(), errs
+}
+
+func TestParsePrecedence(t *testing.T) {
+	got, errs := parseExpr(t, "1 + 2 * 3 < 4 || 5 == 6 && 7")
+	expected := "(((1 + (2 * 3)) < 4) || ((5 == 6) && 7))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseUnaryMinus(t *testing.T) {
+	got, errs := parseExpr(t, "- -2 * 3")
+	expected := "((-(-2)) * 3)"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseAssignIsRightAssociative(t *testing.T) {
+	got, errs := parseExpr(t, "a = b += c + 1")
+	expected := "(a = (b += (c + 1)))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParsePrefixDecrementIsRejected(t *testing.T) {
+	_, errs := parseExpr(t, "--2")
+	expected := []string{"rc has no decrement operator '--', use '-= 1' instead, or '- -' to negate twice"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestParsePostfixIncrementIsRejected(t *testing.T) {
+	_, errs := parse(t, "fn main() -> i32 { let x = 0; x++; ++x; return x; }")
+	expected := []string{
+		"rc has no increment operator '++', use '+= 1' instead",
+		"rc has no increment operator '++', use '+= 1' instead",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestParseIfExpr(t *testing.T) {
+	got, errs := parseExpr(t, "if a < 0 { -1 } else if a == 0 { 0 } else { 1 } * 2")
+	expected := "((if (a < 0) { (-1) } else { (if (a == 0) { 0 } else { 1 }) }) * 2)"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseIfElseChain(t *testing.T) {
+	program, errs := parse(t, "fn main() -> i32 { if a { } else if b { return 1; } else { return 2; } return 0; }")
+	if errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	stmt, ok := program.Functions[0].Body.Stmts[0].(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("Expected an if statement, got %T", program.Functions[0].Body.Stmts[0])
+	}
+	elseIf, ok := stmt.Else.(*ast.IfStmt)
+	if !ok {
+		t.Fatalf("Expected an else-if, got %T", stmt.Else)
+	}
+	if _, ok := elseIf.Else.(*ast.BlockStmt); !ok {
+		t.Errorf("Expected a final else block, got %T", elseIf.Else)
+	}
+}
+
+func TestParseLabeledLoop(t *testing.T) {
+	program, errs := parse(t, "fn main() -> i32 { outer: while 1 { loop { break outer; } } return 0; }")
+	if errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	while, ok := program.Functions[0].Body.Stmts[0].(*ast.WhileStmt)
+	if !ok || while.Label == nil {
+		t.Fatalf("Expected a labeled while loop, got %T", program.Functions[0].Body.Stmts[0])
+	}
+	loop := while.Body.Stmts[0].(*ast.LoopStmt)
+	brk, ok := loop.Body.Stmts[0].(*ast.BreakStmt)
+	if !ok || brk.Label == nil {
+		t.Errorf("Expected a labeled break, got %T", loop.Body.Stmts[0])
+	}
+}
+
+func TestParseForRange(t *testing.T) {
+	program, errs := parse(t, "fn main() -> i32 { for i in 0..n + 1 { } for j in 1..=10 { } return 0; }")
+	if errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	exclusive := program.Functions[0].Body.Stmts[0].(*ast.ForStmt)
+	if exclusive.Inclusive() {
+		t.Errorf("Expected '..' to be an exclusive range")
+	}
+	if _, ok := exclusive.End.(*ast.BinaryExpr); !ok {
+		t.Errorf("Expected the range end to be 'n + 1', got %T", exclusive.End)
+	}
+	inclusive := program.Functions[0].Body.Stmts[1].(*ast.ForStmt)
+	if !inclusive.Inclusive() {
+		t.Errorf("Expected '..=' to be an inclusive range")
+	}
+}
+
+func TestParseRecoversAfterErrors(t *testing.T) {
+	program, errs := parse(t, "fn main() -> i32 { let x = ; let y = 1 let z = 2; x = (1 + ; return y; }")
+	expected := []string{
+		"expected expression",
+		"expected ';' in the end of statement",
+		"expected expression",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+
+	stmts := program.Functions[0].Body.Stmts
+	if _, ok := stmts[len(stmts)-1].(*ast.ReturnStmt); !ok {
+		t.Errorf("Expected parsing to resume at the return statement, got %T", stmts[len(stmts)-1])
+	}
+}
+
+func TestParseMultipleFunctions(t *testing.T) {
+	src := "fn main() -> i32 { return add(1, 2); }\nfn add(a: i32, b: i32,) -> i32 { return a + b; }\nfn zero() -> u8 { return 0; }"
+	program, errs := parse(t, src)
+	if errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	var names []string
+	var params []int
+	for _, fn := range program.Functions {
+		names = append(names, string([]rune(src)[fn.Name.Scope.Start:fn.Name.Scope.End+1]))
+		params = append(params, len(fn.Params))
+	}
+	if expected := []string{"main", "add", "zero"}; !slices.Equal(names, expected) {
+		t.Errorf("Expected: %v, got %v", expected, names)
+	}
+	if expected := []int{0, 2, 0}; !slices.Equal(params, expected) {
+		t.Errorf("Expected: %v parameters, got %v", expected, params)
+	}
+}
+
+func TestParseRecoversAtNextFunction(t *testing.T) {
+	program, errs := parse(t, "fn f( -> i32 { return 0; fn main() -> i32 { return f(); }")
+	expected := []string{"expected parameter name", "expected '}'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+	if len(program.Functions) != 2 {
+		t.Errorf("Expected 2 functions, got %d", len(program.Functions))
+	}
+}
+
+func TestParseStopsAtErrorLimit(t *testing.T) {
+	src := "fn main() -> i32 {" + strings.Repeat(" let = 1;", 50) + " }"
+	_, errs := parse(t, src)
+	if len(errs) != 20 {
+		t.Errorf("Expected parsing to stop after 20 errors, got %d", len(errs))
+	}
+}
+
+func TestParseCast(t *testing.T) {
+	got, errs := parseExpr(t, "-a as u8 * b as i64")
+	expected := "(((-a) as u8) * (b as i64))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseBoolLiterals(t *testing.T) {
+	got, errs := parseExpr(t, "true || false && x")
+	expected := "(true || (false && x))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseBitwisePrecedence(t *testing.T) {
+	got, errs := parseExpr(t, "a | b ^ c & d << 1 + 2 == e")
+	expected := "((a | (b ^ (c & (d << (1 + 2))))) == e)"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseBitwiseCompoundAssignment(t *testing.T) {
+	got, errs := parseExpr(t, "a <<= b &= c | 1")
+	expected := "(a <<= (b &= (c | 1)))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseLogicalNot(t *testing.T) {
+	got, errs := parseExpr(t, "!a && !!b == c || !f(x) as i32")
+	expected := "(((!a) && ((!(!b)) == c)) || ((!f(x)) as i32))"
+	if got != expected || errs != nil {
+		t.Errorf("Expected: %v, got %v (errors: %v)", expected, got, errs)
+	}
+}
+
+func TestParseLogicalNotNeedsOperand(t *testing.T) {
+	_, errs := parseExpr(t, "!")
+	expected := []string{"expected expression"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestPrintNonASCIISource(t *testing.T) {
+	src := "// café ☕\nfn main() -> i32 { let x = 1; return -x + 2 as i32; }"
+	program, errs := parse(t, src)
+	if errs != nil {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	var sb strings.Builder
+	program.Print([]rune(src), &sb, 0)
+	for _, expected := range []string{"Name: main\n", "let x = 1;\n", "return ((-x) + (2 as i32));\n"} {
+		if !strings.Contains(sb.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, sb.String())
+		}
+	}
+}
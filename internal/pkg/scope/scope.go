@@ -1,8 +1,12 @@
 package scope
 
+import "github.com/Mixturka/rc/internal/pkg/source"
+
 // Start and End fields are counted as number of runes from source beginning
 type Scope struct {
 	Start int
 	End   int
 	Line  int
+	// File is the file Start and End are offsets into.
+	File source.FileID
 }
@@ -0,0 +1,48 @@
+package scope
+
+// SymbolTable maps names to the symbols visible at the current point of a
+// walk over nested lexical scopes. Inner scopes shadow outer ones.
+type SymbolTable[T any] struct {
+	scopes []map[string]T
+}
+
+func NewSymbolTable[T any]() *SymbolTable[T] {
+	return &SymbolTable[T]{}
+}
+
+func (st *SymbolTable[T]) Enter() {
+	st.scopes = append(st.scopes, make(map[string]T))
+}
+
+func (st *SymbolTable[T]) Leave() {
+	if len(st.scopes) > 0 {
+		st.scopes = st.scopes[:len(st.scopes)-1]
+	}
+}
+
+// Declare binds name to sym in the innermost scope. If name is already
+// declared in that scope, the existing symbol is returned with ok == false
+// and nothing is changed.
+func (st *SymbolTable[T]) Declare(name string, sym T) (prev T, ok bool) {
+	if len(st.scopes) == 0 {
+		st.Enter()
+	}
+
+	innermost := st.scopes[len(st.scopes)-1]
+	if prev, exists := innermost[name]; exists {
+		return prev, false
+	}
+	innermost[name] = sym
+
+	return prev, true
+}
+
+func (st *SymbolTable[T]) Lookup(name string) (sym T, ok bool) {
+	for i := len(st.scopes) - 1; i >= 0; i-- {
+		if sym, ok := st.scopes[i][name]; ok {
+			return sym, true
+		}
+	}
+
+	return sym, false
+}
@@ -0,0 +1,48 @@
+package scope_test
+
+import (
+	"testing"
+
+	"github.com/Mixturka/rc/internal/pkg/scope"
+)
+
+func TestSymbolTableShadowing(t *testing.T) {
+	st := scope.NewSymbolTable[int]()
+	st.Enter()
+	st.Declare("x", 1)
+
+	st.Enter()
+	if _, ok := st.Declare("x", 2); !ok {
+		t.Errorf("Expected inner scope to shadow 'x'")
+	}
+	if sym, _ := st.Lookup("x"); sym != 2 {
+		t.Errorf("Expected: 2, got %v", sym)
+	}
+	st.Leave()
+
+	if sym, _ := st.Lookup("x"); sym != 1 {
+		t.Errorf("Expected: 1, got %v", sym)
+	}
+}
+
+func TestSymbolTableRedeclaration(t *testing.T) {
+	st := scope.NewSymbolTable[int]()
+	st.Enter()
+	st.Declare("x", 1)
+
+	prev, ok := st.Declare("x", 2)
+	if ok || prev != 1 {
+		t.Errorf("Expected redeclaration to fail with previous symbol 1, got %v, %v", prev, ok)
+	}
+}
+
+func TestSymbolTableLookupMissing(t *testing.T) {
+	st := scope.NewSymbolTable[int]()
+	st.Enter()
+	st.Declare("x", 1)
+	st.Leave()
+
+	if _, ok := st.Lookup("x"); ok {
+		t.Errorf("Expected 'x' to be out of scope")
+	}
+}
@@ -0,0 +1,105 @@
+package source
+
+import "sort"
+
+// TabWidth is the distance between tab stops when computing display
+// columns.
+const TabWidth = 8
+
+// FileID identifies a File within its FileSet. The first file added to a
+// set gets ID 0.
+type FileID int32
+
+// File is a source file along with an index of where its lines start, so
+// offsets can be mapped to lines and columns without rescanning it.
+type File struct {
+	ID   FileID
+	Path string
+	Src  []rune
+	// lineStarts holds the offset of the first rune of every line.
+	lineStarts []int
+}
+
+func NewFile(id FileID, path string, src []rune) *File {
+	lineStarts := []int{0}
+	for i, ch := range src {
+		if ch == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	return &File{ID: id, Path: path, Src: src, lineStarts: lineStarts}
+}
+
+// Position is a location in a File. All fields are 1-based.
+type Position struct {
+	Line int
+	// Column counts Unicode code points, so a multi-byte UTF-8 character
+	// takes a single column.
+	Column int
+	// DisplayColumn is the column as shown by a terminal, with tabs
+	// expanded to the next multiple of TabWidth.
+	DisplayColumn int
+}
+
+// Position maps a rune offset to a Position. Offsets past the end of the
+// file map to the position just after its last character.
+func (f *File) Position(offset int) Position {
+	offset = min(max(offset, 0), len(f.Src))
+	line := sort.Search(len(f.lineStarts), func(i int) bool { return f.lineStarts[i] > offset })
+	start := f.lineStarts[line-1]
+
+	display := 0
+	for _, ch := range f.Src[start:offset] {
+		if ch == '\t' {
+			display += TabWidth - display%TabWidth
+		} else {
+			display++
+		}
+	}
+
+	return Position{Line: line, Column: offset - start + 1, DisplayColumn: display + 1}
+}
+
+func (f *File) LineCount() int {
+	return len(f.lineStarts)
+}
+
+// Line returns the 1-based line n without its line break.
+func (f *File) Line(n int) []rune {
+	start := f.lineStarts[n-1]
+	end := len(f.Src)
+	if n < len(f.lineStarts) {
+		end = f.lineStarts[n] - 1
+	}
+	if end > start && f.Src[end-1] == '\r' {
+		end--
+	}
+
+	return f.Src[start:end]
+}
+
+// FileSet holds all files of a compilation, indexed by their ID.
+type FileSet struct {
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{}
+}
+
+func (fs *FileSet) Add(path string, src []rune) *File {
+	f := NewFile(FileID(len(fs.files)), path, src)
+	fs.files = append(fs.files, f)
+
+	return f
+}
+
+// File returns the file with the given ID, or nil if there is none.
+func (fs *FileSet) File(id FileID) *File {
+	if id < 0 || int(id) >= len(fs.files) {
+		return nil
+	}
+
+	return fs.files[id]
+}
@@ -0,0 +1,53 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+func TestPosition(t *testing.T) {
+	f := source.NewFile(0, "main.rc", []rune("fn main() {\n\tlet é = 1;\r\n}"))
+
+	tests := []struct {
+		offset   int
+		expected source.Position
+	}{
+		{0, source.Position{Line: 1, Column: 1, DisplayColumn: 1}},
+		{11, source.Position{Line: 1, Column: 12, DisplayColumn: 12}},
+		{12, source.Position{Line: 2, Column: 1, DisplayColumn: 1}},
+		{13, source.Position{Line: 2, Column: 2, DisplayColumn: 9}},
+		{19, source.Position{Line: 2, Column: 8, DisplayColumn: 15}},
+		{25, source.Position{Line: 3, Column: 1, DisplayColumn: 1}},
+		{100, source.Position{Line: 3, Column: 2, DisplayColumn: 2}},
+	}
+	for _, test := range tests {
+		if got := f.Position(test.offset); got != test.expected {
+			t.Errorf("Expected: %v at offset %d, got %v", test.expected, test.offset, got)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	f := source.NewFile(0, "main.rc", []rune("a\r\nbc\n\nd"))
+
+	expected := []string{"a", "bc", "", "d"}
+	if f.LineCount() != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), f.LineCount())
+	}
+	for i, line := range expected {
+		if got := string(f.Line(i + 1)); got != line {
+			t.Errorf("Expected: %q for line %d, got %q", line, i+1, got)
+		}
+	}
+}
+
+func TestFileSet(t *testing.T) {
+	fs := source.NewFileSet()
+	a := fs.Add("a.rc", nil)
+	b := fs.Add("b.rc", nil)
+
+	if a.ID != 0 || b.ID != 1 || fs.File(1) != b || fs.File(2) != nil {
+		t.Errorf("Expected files to be numbered in the order they were added")
+	}
+}
@@ -0,0 +1,572 @@
+package sema
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/scope"
+	"github.com/Mixturka/rc/internal/pkg/source"
+	"github.com/Mixturka/rc/internal/types"
+)
+
+// Checker gives types to the variables and literals of a resolved program
+// and reports values of one type used where another is expected. rc never
+// converts between integer types implicitly, that takes 'as'.
+type Checker struct {
+	reporter
+	// fn is the function being checked.
+	fn *ast.Func
+}
+
+// NewChecker returns a checker for the program parsed from file.
+func NewChecker(errEmitter *erremitter.ErrEmitter, file *source.File) Checker {
+	return Checker{reporter: reporter{errEmitter: errEmitter, file: file}}
+}
+
+func (c *Checker) Check(program *ast.Program) {
+	// Calls need the signatures of functions defined later, so resolve all
+	// of them first.
+	for _, fn := range program.Functions {
+		c.resolveType(fn.ReturnType)
+		for _, param := range fn.Params {
+			c.resolveType(param.Type)
+		}
+	}
+
+	for _, fn := range program.Functions {
+		c.fn = fn
+		c.checkStmt(fn.Body)
+		if !returns(fn.Body) {
+			c.errorAt(erremitter.CodeMissingReturn, "function may end without returning a value", fn.Body.RBrace.Scope,
+				c.note(fmt.Sprintf("'%s' returns '%s'", c.text(fn.Name), declaredType(fn.ReturnType)), c.nodeScope(fn.ReturnType)))
+		}
+	}
+
+	c.checkMain(program)
+}
+
+// checkMain reports a program without a 'main' function, or with one the
+// emitted C can't be started from. A second 'main' is already reported as a
+// duplicate, so only the first one is checked.
+func (c *Checker) checkMain(program *ast.Program) {
+	i := slices.IndexFunc(program.Functions, func(fn *ast.Func) bool { return c.text(fn.Name) == "main" })
+	if i < 0 {
+		end := len(c.file.Src)
+		c.errorAt(erremitter.CodeInvalidMain, "no 'main' function in the program",
+			scope.Scope{Start: end, End: end, Line: c.file.Position(end).Line, File: c.file.ID},
+			help("add 'fn main() -> i32 { ... }', the program starts there"))
+		return
+	}
+
+	main := program.Functions[i]
+	var children []erremitter.Child
+	if len(main.Params) > 0 {
+		children = append(children, c.note("'main' takes no parameters",
+			scope.Scope{Start: main.Params[0].ScopeStart(), End: main.Params[len(main.Params)-1].ScopeEnd(), File: c.file.ID}))
+	}
+	if result := declaredType(main.ReturnType); result != types.I32 && result != types.Invalid {
+		children = append(children, c.note(fmt.Sprintf("'main' returns the exit code as '%s', not '%s'", types.I32, result),
+			c.nodeScope(main.ReturnType)))
+	}
+	if len(children) > 0 {
+		c.errorAt(erremitter.CodeInvalidMain, "'main' must be declared as 'fn main() -> i32'", main.Name.Scope, children...)
+	}
+}
+
+// returns reports whether stmt always ends in a 'return': it is one, it is
+// a block containing one, an 'if' whose branches both return, or a 'loop'
+// that no 'break' leaves. Conditions are never evaluated, so a 'while'
+// loop is not known to loop forever even if its condition is constant.
+func returns(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.BlockStmt:
+		return slices.ContainsFunc(stmt.Stmts, returns)
+	case *ast.IfStmt:
+		return stmt.Else != nil && returns(stmt.Then) && returns(stmt.Else)
+	case *ast.LoopStmt:
+		return !breaks(stmt.Body, stmt)
+	}
+
+	return false
+}
+
+// breaks reports whether stmt contains a 'break' leaving loop.
+func breaks(stmt ast.Stmt, loop ast.Loop) bool {
+	switch stmt := stmt.(type) {
+	case *ast.BreakStmt:
+		return stmt.Target == loop
+	case *ast.BlockStmt:
+		return slices.ContainsFunc(stmt.Stmts, func(s ast.Stmt) bool { return breaks(s, loop) })
+	case *ast.IfStmt:
+		return breaks(stmt.Then, loop) || stmt.Else != nil && breaks(stmt.Else, loop)
+	case *ast.WhileStmt:
+		return breaks(stmt.Body, loop)
+	case *ast.LoopStmt:
+		return breaks(stmt.Body, loop)
+	case *ast.ForStmt:
+		return breaks(stmt.Body, loop)
+	}
+
+	return false
+}
+
+func (c *Checker) checkStmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.BlockStmt:
+		for _, s := range stmt.Stmts {
+			c.checkStmt(s)
+		}
+	case *ast.LetStmt:
+		if stmt.Type == nil {
+			stmt.VarType = c.checkExpr(stmt.Value, types.Invalid)
+			return
+		}
+		stmt.VarType = c.resolveType(stmt.Type)
+		c.expect(stmt.Value, c.checkExpr(stmt.Value, stmt.VarType), stmt.VarType,
+			c.note(fmt.Sprintf("'%s' is declared as '%s' here", c.text(stmt.Name), stmt.VarType), c.nodeScope(stmt.Type)))
+	case *ast.IfStmt:
+		c.checkCond(stmt.Cond)
+		c.checkStmt(stmt.Then)
+		if stmt.Else != nil {
+			c.checkStmt(stmt.Else)
+		}
+	case *ast.WhileStmt:
+		c.checkCond(stmt.Cond)
+		c.checkStmt(stmt.Body)
+	case *ast.LoopStmt:
+		c.checkStmt(stmt.Body)
+	case *ast.ForStmt:
+		start, end := c.checkOperands(stmt.Start, stmt.End, types.Invalid)
+		c.sameType(fmt.Sprintf("mismatched types '%s' and '%s' in the bounds of the range", start, end),
+			stmt.Start, stmt.End, start, end)
+		stmt.VarType = start
+		if start == types.Invalid {
+			stmt.VarType = end
+		}
+		c.checkStmt(stmt.Body)
+	case *ast.ExprStmt:
+		c.checkExpr(stmt.Expr, types.Invalid)
+	case *ast.ReturnStmt:
+		result := declaredType(c.fn.ReturnType)
+		c.expect(stmt.Expr, c.checkExpr(stmt.Expr, result), result,
+			c.note(fmt.Sprintf("'%s' returns '%s'", c.text(c.fn.Name), result), c.nodeScope(c.fn.ReturnType)))
+	}
+}
+
+// checkExpr records the type of expr in the AST and returns it. want is the
+// type the context expects, or Invalid if it expects none. Literals take
+// that type, which is all it is used for: the caller reports a mismatch
+// itself.
+func (c *Checker) checkExpr(expr ast.Expr, want types.Type) types.Type {
+	typ := c.exprType(expr, want)
+	expr.SetType(typ)
+	return typ
+}
+
+func (c *Checker) exprType(expr ast.Expr, want types.Type) types.Type {
+	switch expr := expr.(type) {
+	case *ast.ConstExpr:
+		return c.checkLiteral(expr, expr, want)
+	case *ast.VarExpr:
+		return varType(expr.Decl)
+	case *ast.UnaryExpr:
+		return c.checkUnary(expr, want)
+	case *ast.BinaryExpr:
+		return c.checkBinary(expr, want)
+	case *ast.AssignExpr:
+		return c.checkAssign(expr)
+	case *ast.CallExpr:
+		return c.checkCall(expr)
+	case *ast.IfExpr:
+		c.checkCond(expr.Cond)
+		then, els := c.checkOperands(expr.Then, expr.Else, want)
+		c.sameType(fmt.Sprintf("'if' and 'else' have mismatched types '%s' and '%s'", then, els), expr.Then, expr.Else, then, els)
+		if then == types.Invalid {
+			return els
+		}
+		return then
+	case *ast.BoolExpr:
+		return types.Bool
+	case *ast.CastExpr:
+		from := c.checkExpr(expr.Expr, types.Invalid)
+		to := c.resolveType(expr.Target)
+		// Only bool to integer is allowed of the conversions involving bool,
+		// as it can't lose information.
+		if to == types.Bool && from.IsInteger() {
+			c.errorAt(erremitter.CodeInvalidCast, fmt.Sprintf("cannot convert '%s' to '%s' with 'as'", from, to), c.nodeScope(expr),
+				help("compare it with zero instead, e.g. 'x != 0'"))
+		}
+		return to
+	}
+
+	return types.Invalid
+}
+
+func (c *Checker) checkUnary(expr *ast.UnaryExpr, want types.Type) types.Type {
+	// A negated literal is checked as a whole, so '-128' fits in i8 and
+	// '-1' doesn't fit in u8.
+	if lit, ok := expr.Rhs.(*ast.ConstExpr); ok && expr.Op.Type == token.Minus {
+		return c.checkLiteral(lit, expr, want)
+	}
+
+	if expr.Op.Type == token.Not {
+		operand := c.checkExpr(expr.Rhs, types.Bool)
+		if operand.IsInteger() {
+			c.errorAt(erremitter.CodeInvalidOperand, fmt.Sprintf("cannot apply '!' to a value of type '%s'", operand), c.nodeScope(expr),
+				help("use '~' to flip the bits of an integer, or 'x == 0' to test it for zero"))
+		}
+		return types.Bool
+	}
+
+	operand := c.checkExpr(expr.Rhs, want)
+	if expr.Op.Type == token.Tilde && operand == types.Bool {
+		c.errorAt(erremitter.CodeInvalidOperand, fmt.Sprintf("cannot apply '~' to a value of type '%s'", operand), c.nodeScope(expr),
+			help("use '!' to negate a bool"))
+		return types.Invalid
+	}
+	if !c.requireInteger(expr.Op, expr.Rhs, operand) {
+		return types.Invalid
+	}
+	if expr.Op.Type == token.Minus && !operand.IsSigned() {
+		c.errorAt(erremitter.CodeInvalidOperand, fmt.Sprintf("cannot negate a value of unsigned type '%s'", operand), c.nodeScope(expr),
+			help("convert it to a signed type with 'as' first"))
+	}
+
+	return operand
+}
+
+func (c *Checker) checkAssign(expr *ast.AssignExpr) types.Type {
+	target := c.checkExpr(expr.Target, types.Invalid)
+
+	if expr.Op.Type == token.LessLessAssign || expr.Op.Type == token.GreaterGreaterAssign {
+		c.checkShift(expr.Op, expr.Target, expr.Value, target)
+		return target
+	}
+
+	var related []erremitter.Child
+	if v, ok := expr.Target.(*ast.VarExpr); ok {
+		related = append(related, c.note(fmt.Sprintf("'%s' has type '%s'", c.text(v.Name), target), c.nodeScope(v)))
+	}
+	value := c.checkExpr(expr.Value, target)
+	if expr.Op.Type != token.Assign && !c.requireInteger(expr.Op, expr.Target, target) {
+		return target
+	}
+	c.expect(expr.Value, value, target, related...)
+	c.checkDivisor(expr.Op, expr.Value)
+
+	return target
+}
+
+func (c *Checker) checkBinary(expr *ast.BinaryExpr, want types.Type) types.Type {
+	op := c.text(expr.Op)
+
+	switch expr.Op.Type {
+	case token.LessLess, token.GreaterGreater:
+		lhs := c.checkExpr(expr.Lhs, want)
+		if !c.checkShift(expr.Op, expr.Lhs, expr.Rhs, lhs) {
+			return types.Invalid
+		}
+		return lhs
+	case token.AmpersandAmpersand, token.BarBar:
+		for _, operand := range []ast.Expr{expr.Lhs, expr.Rhs} {
+			c.expect(operand, c.checkExpr(operand, types.Bool), types.Bool,
+				c.note(fmt.Sprintf("both operands of '%s' must be '%s'", op, types.Bool), expr.Op.Scope))
+		}
+		return types.Bool
+	case token.Equals, token.NotEquals:
+		lhs, rhs := c.checkOperands(expr.Lhs, expr.Rhs, types.Invalid)
+		c.sameType(fmt.Sprintf("mismatched types '%s' and '%s' in '%s'", lhs, rhs, op), expr.Lhs, expr.Rhs, lhs, rhs)
+		return types.Bool
+	case token.Less, token.LessEqual, token.Greater, token.GreaterEqual:
+		lhs, rhs := c.checkOperands(expr.Lhs, expr.Rhs, types.Invalid)
+		if c.requireInteger(expr.Op, expr.Lhs, lhs) && c.requireInteger(expr.Op, expr.Rhs, rhs) {
+			c.sameType(fmt.Sprintf("mismatched types '%s' and '%s' in '%s'", lhs, rhs, op), expr.Lhs, expr.Rhs, lhs, rhs)
+		}
+		return types.Bool
+	}
+
+	lhs, rhs := c.checkOperands(expr.Lhs, expr.Rhs, want)
+	if !c.requireInteger(expr.Op, expr.Lhs, lhs) || !c.requireInteger(expr.Op, expr.Rhs, rhs) {
+		return types.Invalid
+	}
+	same := c.sameType(fmt.Sprintf("mismatched types '%s' and '%s' in '%s'", lhs, rhs, op), expr.Lhs, expr.Rhs, lhs, rhs)
+	c.checkDivisor(expr.Op, expr.Rhs)
+	// The mismatch is already reported, the result has no type for the
+	// context to report another one about.
+	if !same {
+		return types.Invalid
+	}
+	if lhs == types.Invalid {
+		return rhs
+	}
+	return lhs
+}
+
+// checkShift checks the amount of a shift of value, of type typ, by op. The
+// amount can be of any integer type, as it doesn't mix with the value, but
+// a constant amount must be less than the width of typ: C leaves larger
+// shifts undefined. It returns whether both operands are integers.
+func (c *Checker) checkShift(op token.Token, value, amount ast.Expr, typ types.Type) bool {
+	amountType := c.checkExpr(amount, types.Invalid)
+	if !c.requireInteger(op, value, typ) || !c.requireInteger(op, amount, amountType) {
+		return false
+	}
+
+	v, ok := c.constValue(amount)
+	if !ok || typ == types.Invalid {
+		return true
+	}
+	switch {
+	case v.Sign() < 0:
+		c.errorAt(erremitter.CodeShiftOutOfRange, fmt.Sprintf("cannot shift by the negative amount %s", v), c.nodeScope(amount))
+	case v.Cmp(big.NewInt(int64(typ.Bits()))) >= 0:
+		c.errorAt(erremitter.CodeShiftOutOfRange, fmt.Sprintf("shift by %s is too large for type '%s'", v, typ), c.nodeScope(amount),
+			help(fmt.Sprintf("'%s' is %d bits wide, so it can be shifted by at most %d", typ, typ.Bits(), typ.Bits()-1)))
+	}
+
+	return true
+}
+
+// checkDivisor reports a divisor of op that is a constant zero. Other
+// divisors are checked when the program runs.
+func (c *Checker) checkDivisor(op token.Token, divisor ast.Expr) {
+	switch op.Type {
+	case token.Slash, token.SlashAssign, token.Percent:
+	default:
+		return
+	}
+
+	if v, ok := c.constValue(divisor); ok && v.Sign() == 0 {
+		c.errorAt(erremitter.CodeDivisionByZero, "attempt to divide by zero", c.nodeScope(divisor),
+			c.note(fmt.Sprintf("'%s' by zero would abort the program", c.text(op)), op.Scope))
+	}
+}
+
+// constValue returns the value of expr if it is known without running the
+// program: an integer literal, or '+', '-', '*', '/' and '%' of known values.
+// The value is computed exactly, as if the operations could not overflow,
+// which is enough to tell a zero divisor or a shift amount out of range.
+func (c *Checker) constValue(expr ast.Expr) (*big.Int, bool) {
+	switch expr := expr.(type) {
+	case *ast.ConstExpr:
+		v, _ := new(big.Int).SetString(c.text(expr.Value), 10)
+		return v, true
+	case *ast.UnaryExpr:
+		if v, ok := c.constValue(expr.Rhs); ok && expr.Op.Type == token.Minus {
+			return v.Neg(v), true
+		}
+	case *ast.BinaryExpr:
+		lhs, ok := c.constValue(expr.Lhs)
+		if !ok {
+			return nil, false
+		}
+		rhs, ok := c.constValue(expr.Rhs)
+		if !ok {
+			return nil, false
+		}
+		switch expr.Op.Type {
+		case token.Plus:
+			return lhs.Add(lhs, rhs), true
+		case token.Minus:
+			return lhs.Sub(lhs, rhs), true
+		case token.Star:
+			return lhs.Mul(lhs, rhs), true
+		case token.Slash, token.Percent:
+			// A zero divisor is reported on its own, the division has no
+			// value. Quo and Rem truncate towards zero like C.
+			if rhs.Sign() == 0 {
+				return nil, false
+			}
+			if expr.Op.Type == token.Slash {
+				return lhs.Quo(lhs, rhs), true
+			}
+			return lhs.Rem(lhs, rhs), true
+		}
+	}
+
+	return nil, false
+}
+
+// requireInteger reports operand, of type typ, if op doesn't work on it
+// because it isn't an integer. It returns whether typ can be used.
+func (c *Checker) requireInteger(op token.Token, operand ast.Expr, typ types.Type) bool {
+	if typ == types.Invalid || typ.IsInteger() {
+		return true
+	}
+
+	c.errorAt(erremitter.CodeInvalidOperand, fmt.Sprintf("cannot apply '%s' to a value of type '%s'", c.text(op), typ),
+		c.nodeScope(operand), c.note(fmt.Sprintf("'%s' only works on integers", c.text(op)), op.Scope))
+	return false
+}
+
+func (c *Checker) checkCall(call *ast.CallExpr) types.Type {
+	// The resolver has reported calls that don't match a function.
+	if call.Func == nil || len(call.Args) != len(call.Func.Params) {
+		for _, arg := range call.Args {
+			c.checkExpr(arg, types.Invalid)
+		}
+		if call.Func == nil {
+			return types.Invalid
+		}
+		return declaredType(call.Func.ReturnType)
+	}
+
+	for i, arg := range call.Args {
+		param := call.Func.Params[i]
+		want := declaredType(param.Type)
+		c.expect(arg, c.checkExpr(arg, want), want,
+			c.note(fmt.Sprintf("parameter '%s' has type '%s'", c.text(param.Name), want), c.nodeScope(param)))
+	}
+
+	return declaredType(call.Func.ReturnType)
+}
+
+// checkCond checks the condition of an 'if' or a 'while', which has to be a
+// bool.
+func (c *Checker) checkCond(cond ast.Expr) {
+	c.expect(cond, c.checkExpr(cond, types.Bool), types.Bool)
+}
+
+// checkOperands returns the types of two expressions that should have the
+// same type. A literal takes the type of the other side, so '1 + x' works
+// whatever the type of x.
+func (c *Checker) checkOperands(lhs, rhs ast.Expr, want types.Type) (types.Type, types.Type) {
+	if isUntyped(lhs) && !isUntyped(rhs) {
+		r := c.checkExpr(rhs, want)
+		return c.checkExpr(lhs, r), r
+	}
+
+	l := c.checkExpr(lhs, want)
+	return l, c.checkExpr(rhs, l)
+}
+
+// checkLiteral gives lit the type want, or i32 if want is Invalid, and
+// reports it if its value doesn't fit. expr is the literal itself or its
+// negation.
+func (c *Checker) checkLiteral(lit *ast.ConstExpr, expr ast.Expr, want types.Type) types.Type {
+	lit.SetType(want)
+	if !want.IsInteger() {
+		lit.SetType(types.I32)
+	}
+
+	// The lexer only lets ASCII digits into a literal, so it always has a
+	// value, but a bad one is still better reported than crashed on.
+	v, ok := c.constValue(expr)
+	if !ok || v == nil {
+		c.errorAt(erremitter.CodeSyntax, fmt.Sprintf("invalid integer literal '%s'", c.text(lit.Value)), lit.Value.Scope)
+		return lit.Type
+	}
+	if !lit.Type.Fits(v) {
+		c.errorAt(erremitter.CodeLiteralOutOfRange, fmt.Sprintf("literal %s does not fit in type '%s'", v, lit.Type), c.nodeScope(expr),
+			help(fmt.Sprintf("'%s' holds values from %s to %s", lit.Type, lit.Type.Min(), lit.Type.Max())))
+	}
+
+	return lit.Type
+}
+
+// isUntyped reports whether expr is made up of literals only, so that its
+// type comes from the context.
+func isUntyped(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.ConstExpr:
+		return true
+	case *ast.UnaryExpr:
+		return expr.Op.Type != token.Not && isUntyped(expr.Rhs)
+	case *ast.BinaryExpr:
+		switch expr.Op.Type {
+		case token.Plus, token.Minus, token.Star, token.Slash, token.Percent, token.Ampersand, token.Bar, token.Caret:
+			return isUntyped(expr.Lhs) && isUntyped(expr.Rhs)
+		case token.LessLess, token.GreaterGreater:
+			// The amount doesn't affect the type of a shift.
+			return isUntyped(expr.Lhs)
+		}
+	}
+
+	return false
+}
+
+// expect reports expr, of type got, used where a value of type want is
+// required. related explains where want comes from.
+func (c *Checker) expect(expr ast.Expr, got, want types.Type, related ...erremitter.Child) {
+	if got == want || got == types.Invalid || want == types.Invalid {
+		return
+	}
+
+	children := related
+	switch {
+	case got.IsInteger() && want.IsInteger():
+		children = append(children, help(fmt.Sprintf("convert the value with 'as %s'", want)))
+	case got == types.Bool && want.IsInteger():
+		children = append(children, help(fmt.Sprintf("convert it with 'as %s' to get 1 or 0", want)))
+	case got.IsInteger() && want == types.Bool:
+		children = append(children, help("compare it with zero, e.g. 'x != 0'"))
+	}
+	c.errorAt(erremitter.CodeMismatchedTypes, fmt.Sprintf("mismatched types: expected '%s', found '%s'", want, got),
+		c.nodeScope(expr), children...)
+}
+
+// sameType reports two expressions that should have the same type but
+// don't, with a squiggle under each of them. It returns whether the types
+// agree.
+func (c *Checker) sameType(message string, lhs, rhs ast.Expr, lt, rt types.Type) bool {
+	if lt == rt || lt == types.Invalid || rt == types.Invalid {
+		return true
+	}
+
+	at := c.nodeScope(lhs)
+	c.errEmitter.Add(erremitter.Err{
+		Severity:  erremitter.Error,
+		Code:      erremitter.CodeMismatchedTypes,
+		Message:   message,
+		ErrScope:  erremitter.ErrScope{File: at.File, Start: at.Start, End: at.End},
+		Squiggles: []erremitter.SquiggleScope{c.squiggle(at), c.squiggle(c.nodeScope(rhs))},
+		Children:  []erremitter.Child{help("convert one of them with 'as' so both have the same type")},
+	})
+	return false
+}
+
+// resolveType returns the type named by typ and records it in the AST.
+func (c *Checker) resolveType(typ ast.TypeExpr) types.Type {
+	named, ok := typ.(*ast.NamedType)
+	if !ok {
+		return types.Invalid
+	}
+
+	name := c.text(named.Name)
+	t, ok := types.Lookup(name)
+	if !ok {
+		c.errorAt(erremitter.CodeUnknownType, fmt.Sprintf("unknown type '%s'", name), named.Name.Scope,
+			help("the built-in types are i8, i16, i32, i64, isize, u8, u16, u32, u64 and usize"))
+	}
+	named.Type = t
+
+	return t
+}
+
+// declaredType returns the type typ was resolved to.
+func declaredType(typ ast.TypeExpr) types.Type {
+	if named, ok := typ.(*ast.NamedType); ok {
+		return named.Type
+	}
+
+	return types.Invalid
+}
+
+func varType(decl ast.VarDecl) types.Type {
+	switch decl := decl.(type) {
+	case *ast.Param:
+		return declaredType(decl.Type)
+	case *ast.LetStmt:
+		return decl.VarType
+	case *ast.ForStmt:
+		return decl.VarType
+	}
+
+	return types.Invalid
+}
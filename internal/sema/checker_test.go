@@ -0,0 +1,302 @@
+package sema_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer"
+	"github.com/Mixturka/rc/internal/parser"
+	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/source"
+	"github.com/Mixturka/rc/internal/sema"
+	"github.com/Mixturka/rc/internal/types"
+)
+
+// check resolves and type checks src and returns the program along with
+// the messages of the errors found.
+func check(t *testing.T, src string) (*ast.Program, []string) {
+	t.Helper()
+
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(src), &em)
+	toks := l.Tokenize()
+	p := parser.NewParser(toks, &em, []rune(src))
+	program := p.Parse()
+
+	file := source.NewFile(0, "test.rc", []rune(src))
+	r := sema.NewResolver(&em, file)
+	r.Resolve(program)
+	c := sema.NewChecker(&em, file)
+	c.Check(program)
+
+	var messages []string
+	for _, e := range em.Errors() {
+		if e.Severity == erremitter.Error {
+			messages = append(messages, e.Message)
+		}
+	}
+	return program, messages
+}
+
+func TestCheckVariableTypes(t *testing.T) {
+	program, errs := check(t, "fn main() -> i32 { let a: u8 = 1; let b = a + 2; let c = 3; for i in 0..b { } return c; }")
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	stmts := program.Functions[0].Body.Stmts
+	got := []types.Type{
+		stmts[0].(*ast.LetStmt).VarType,
+		stmts[1].(*ast.LetStmt).VarType,
+		stmts[2].(*ast.LetStmt).VarType,
+		stmts[3].(*ast.ForStmt).VarType,
+	}
+	expected := []types.Type{types.U8, types.U8, types.I32, types.U8}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected: %v, got %v", expected, got)
+	}
+}
+
+func TestCheckLiteralRange(t *testing.T) {
+	_, errs := check(t, `fn main() -> i32 {
+		let a: u8 = 255; let b: u8 = 256; let c: i8 = -128; let d: i8 = -129; let e: u32 = -1;
+		let f: u64 = 18446744073709551615; let g = 2147483648;
+		return 0;
+	}`)
+	expected := []string{
+		"literal 256 does not fit in type 'u8'",
+		"literal -129 does not fit in type 'i8'",
+		"literal -1 does not fit in type 'u32'",
+		"literal 2147483648 does not fit in type 'i32'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckMismatchedTypes(t *testing.T) {
+	_, errs := check(t, `fn f(x: i64) -> u8 { return 1; }
+	fn main() -> i32 {
+		let a: u8 = 1; let b: i32 = 2;
+		let c = a + b; b = a; let d: u16 = f(b); return f(2);
+	}`)
+	expected := []string{
+		"mismatched types 'u8' and 'i32' in '+'",
+		"mismatched types: expected 'i32', found 'u8'",
+		"mismatched types: expected 'i64', found 'i32'",
+		"mismatched types: expected 'u16', found 'u8'",
+		"mismatched types: expected 'i32', found 'u8'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckExplicitConversion(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let a: u8 = 1; let b: i64 = 2; return a as i32 + b as i32 + 300 as u8 as i32; }")
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestCheckUnknownType(t *testing.T) {
+	_, errs := check(t, "fn main() -> int { let x: long = 1; return x; }")
+	expected := []string{"unknown type 'int'", "unknown type 'long'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckRecordsExpressionTypes(t *testing.T) {
+	program, errs := check(t, "fn main() -> i32 { let a: u16 = 1; return (a * 2 > 3) as i32; }")
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+
+	cast := program.Functions[0].Body.Stmts[1].(*ast.ReturnStmt).Expr.(*ast.CastExpr)
+	cmp := cast.Expr.(*ast.BinaryExpr)
+	mul := cmp.Lhs.(*ast.BinaryExpr)
+	got := []types.Type{cast.Type, cmp.Type, mul.Type, mul.Rhs.ExprType(), cmp.Rhs.ExprType()}
+	expected := []types.Type{types.I32, types.Bool, types.U16, types.U16, types.U16}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected: %v, got %v", expected, got)
+	}
+}
+
+func TestCheckOperandTypes(t *testing.T) {
+	_, errs := check(t, `fn main() -> i32 {
+		let n: u32 = 5; let b = n > 1;
+		let m = -n; let k = b + 1; let l = b < b; let o = b == b;
+		return 1 < 2 || 3;
+	}`)
+	expected := []string{
+		"cannot negate a value of unsigned type 'u32'",
+		"cannot apply '+' to a value of type 'bool'",
+		"cannot apply '<' to a value of type 'bool'",
+		"mismatched types: expected 'bool', found 'i32'",
+		"mismatched types: expected 'i32', found 'bool'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckIfExprBranches(t *testing.T) {
+	_, errs := check(t, `fn f() -> i64 {
+		let a: u8 = 1; let x = if a > 0 { 1 } else { a }; let _y = if a > 0 { a } else { 2 as i32 };
+		return if a > 1 { 2 } else { x };
+	}
+	fn main() -> i32 { return f() as i32; }`)
+	expected := []string{"'if' and 'else' have mismatched types 'u8' and 'i32'", "mismatched types: expected 'i64', found 'u8'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckBool(t *testing.T) {
+	program, errs := check(t, "fn f(b: bool) -> bool { return b && true; } fn main() -> i32 { let b = f(false) || 1 < 2; return b as i32; }")
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, got %v", errs)
+	}
+	if got := program.Functions[1].Body.Stmts[0].(*ast.LetStmt).VarType; got != types.Bool {
+		t.Errorf("Expected: %v, got %v", types.Bool, got)
+	}
+}
+
+func TestCheckConditionsAreBool(t *testing.T) {
+	_, errs := check(t, `fn main() -> i32 {
+		let n = 3; let b = n != 0;
+		while n { n -= 1; } if b { } let x = if n { 1 } else { 2 };
+		return x + (b as i32) + (n as bool) as i32;
+	}`)
+	expected := []string{
+		"mismatched types: expected 'bool', found 'i32'",
+		"mismatched types: expected 'bool', found 'i32'",
+		"cannot convert 'i32' to 'bool' with 'as'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckBitwiseOperators(t *testing.T) {
+	_, errs := check(t, `fn main() -> i32 {
+		let a: u8 = 1; let n: i64 = 3; let b = true;
+		let x = a & 15 | a ^ 2; let y = a << n; let z = 1 << a; a >>= n;
+		let w = b | b; let v = a & x as i32;
+		return x as i32 + y as i32 + z + v as i32 + w as i32;
+	}`)
+	expected := []string{
+		"cannot apply '|' to a value of type 'bool'",
+		"mismatched types 'u8' and 'i32' in '&'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckConstantShiftAmount(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let a: u8 = 1; a <<= 7; a <<= 8; let b = 1 << 31; let c = 1 << 32; let d = a >> -1; return 1 as i64 << 63; }")
+	expected := []string{
+		"shift by 8 is too large for type 'u8'",
+		"shift by 32 is too large for type 'i32'",
+		"cannot shift by the negative amount -1",
+		"mismatched types: expected 'i32', found 'i64'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckLogicalNot(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let n = 1; let b = !(n > 0) && !!true; let x = !n; let y = ~b; return (!b) as i32; }")
+	expected := []string{"cannot apply '!' to a value of type 'i32'", "cannot apply '~' to a value of type 'bool'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckConstantDivisor(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let a = 10; a /= 0; let b = a / -0; let c = a % 0; let d = a / 2 % -1; return a / (b - b) + c + d; }")
+	expected := []string{"attempt to divide by zero", "attempt to divide by zero", "attempt to divide by zero"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckMissingReturn(t *testing.T) {
+	_, errs := check(t, `fn g() -> i32 { let _x = 1; }
+	fn h(c: bool) -> i32 { if c { return 1; } }
+	fn w() -> i32 { while true { return 1; } }
+	fn m() -> i32 { outer: loop { loop { break outer; } } }
+	fn main() -> i32 { return g() + h(true) + w() + m(); }`)
+	expected := []string{
+		"function may end without returning a value",
+		"function may end without returning a value",
+		"function may end without returning a value",
+		"function may end without returning a value",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckAllPathsReturn(t *testing.T) {
+	_, errs := check(t, `fn sign(n: i32) -> i32 { if n < 0 { return -1; } else if n > 0 { return 1; } else { return 0; } }
+	fn k() -> i32 { loop { loop { break; } return 1; } }
+	fn b() -> i32 { { return 2; } }
+	fn main() -> i32 { return sign(3) + k() + b(); }`)
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestCheckFoldedConstants(t *testing.T) {
+	_, errs := check(t, `fn main() -> i32 {
+		let x = 10; let a: u8 = 1;
+		let b = x / (1 - 1) + x % (2 * 0) + x / (7 / 0);
+		let c = a << (4 * 2); let d = 1 << (40 - 9); let e = x / (-3 % 2);
+		return b + c as i32 + d + e;
+	}`)
+	expected := []string{
+		"attempt to divide by zero",
+		"attempt to divide by zero",
+		"attempt to divide by zero",
+		"shift by 8 is too large for type 'u8'",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckNonASCIIDigits(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let x = ٣; return 1 + ٣; }")
+	if len(errs) == 0 || errs[0] != "unexpected character '٣'" {
+		t.Errorf("Expected an unexpected character error first, got %v", errs)
+	}
+}
+
+func TestCheckMismatchReportedOnce(t *testing.T) {
+	_, errs := check(t, "fn main() -> i32 { let a: u8 = 1; let b: i32 = 2; let c: i64 = a * b; return a + b; }")
+	expected := []string{"mismatched types 'u8' and 'i32' in '*'", "mismatched types 'u8' and 'i32' in '+'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestCheckMain(t *testing.T) {
+	_, errs := check(t, "fn f() -> i32 { return 0; }")
+	expected := []string{"no 'main' function in the program"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+
+	for _, src := range []string{"fn main(a: i32) -> i32 { return a; }", "fn main() -> u8 { return 0; }"} {
+		_, errs := check(t, src)
+		expected := []string{"'main' must be declared as 'fn main() -> i32'"}
+		if !slices.Equal(errs, expected) {
+			t.Errorf("Expected: %v, got %v", expected, errs)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sema
+
+import (
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/scope"
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+// reporter reports the diagnostics of the passes over a program parsed
+// from file.
+type reporter struct {
+	errEmitter *erremitter.ErrEmitter
+	file       *source.File
+}
+
+func (r *reporter) text(tok token.Token) string {
+	return string(r.file.Src[tok.Scope.Start : tok.Scope.End+1])
+}
+
+// errorAt reports an error spanning at with a squiggle under it, followed
+// by children such as a note pointing at a previous declaration.
+func (r *reporter) errorAt(code erremitter.Code, message string, at scope.Scope, children ...erremitter.Child) {
+	r.errEmitter.Add(erremitter.Err{
+		Severity:  erremitter.Error,
+		Code:      code,
+		Message:   message,
+		ErrScope:  erremitter.ErrScope{File: at.File, Start: at.Start, End: at.End},
+		Squiggles: []erremitter.SquiggleScope{r.squiggle(at)},
+		Children:  children,
+	})
+}
+
+func (r *reporter) warningAt(code erremitter.Code, message string, at scope.Scope, children ...erremitter.Child) {
+	r.errEmitter.Add(erremitter.Err{
+		Severity:  erremitter.Warning,
+		Code:      code,
+		Message:   message,
+		ErrScope:  erremitter.ErrScope{File: at.File, Start: at.Start, End: at.End},
+		Squiggles: []erremitter.SquiggleScope{r.squiggle(at)},
+		Children:  children,
+	})
+}
+
+func (r *reporter) note(message string, at scope.Scope) erremitter.Child {
+	return erremitter.Child{Severity: erremitter.Note, Message: message, Squiggles: []erremitter.SquiggleScope{r.squiggle(at)}}
+}
+
+func help(message string) erremitter.Child {
+	return erremitter.Child{Severity: erremitter.Help, Message: message}
+}
+
+func (r *reporter) squiggle(s scope.Scope) erremitter.SquiggleScope {
+	lines := r.file.Position(s.End).Line - r.file.Position(s.Start).Line + 1
+	return erremitter.SquiggleScope{File: s.File, Start: s.Start, End: s.End, Lines: lines}
+}
+
+// nodeScope returns the scope of a node of the program being checked.
+func (r *reporter) nodeScope(node ast.ScopableNode) scope.Scope {
+	return scope.Scope{Start: node.ScopeStart(), End: node.ScopeEnd(), File: r.file.ID}
+}
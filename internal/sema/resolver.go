@@ -0,0 +1,270 @@
+package sema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer/token"
+	"github.com/Mixturka/rc/internal/parser/ast"
+	"github.com/Mixturka/rc/internal/pkg/scope"
+	"github.com/Mixturka/rc/internal/pkg/source"
+)
+
+// Resolver binds the names used in a program to their declarations and
+// reports the uses that cannot be bound.
+type Resolver struct {
+	reporter
+	funcs   map[string]*ast.Func
+	symbols *scope.SymbolTable[ast.VarDecl]
+	// loops holds the loops enclosing the statement being resolved, the
+	// innermost one last.
+	loops []ast.Loop
+	// lets holds the 'let' statements of the function being resolved, and
+	// read the variables among them that are read somewhere.
+	lets []*ast.LetStmt
+	read map[ast.VarDecl]bool
+}
+
+// NewResolver returns a resolver for the program parsed from file.
+func NewResolver(errEmitter *erremitter.ErrEmitter, file *source.File) Resolver {
+	return Resolver{
+		reporter: reporter{errEmitter: errEmitter, file: file},
+		funcs:    make(map[string]*ast.Func),
+		symbols:  scope.NewSymbolTable[ast.VarDecl](),
+	}
+}
+
+func (r *Resolver) Resolve(program *ast.Program) {
+	// Functions are visible in the whole program, so collect them before
+	// looking at any body.
+	for _, fn := range program.Functions {
+		name := r.text(fn.Name)
+		if prev, ok := r.funcs[name]; ok {
+			r.errorAt(erremitter.CodeDuplicateFunc, fmt.Sprintf("function '%s' is defined more than once", name), fn.Name.Scope,
+				r.note("first defined here", prev.Name.Scope))
+			continue
+		}
+		r.funcs[name] = fn
+	}
+
+	for _, fn := range program.Functions {
+		r.resolveFunc(fn)
+	}
+}
+
+func (r *Resolver) resolveFunc(fn *ast.Func) {
+	// Parameters share a scope with the outermost block of the body, as
+	// they do in C, so the body can't redeclare them.
+	r.symbols.Enter()
+	defer r.symbols.Leave()
+	r.lets = nil
+	r.read = make(map[ast.VarDecl]bool)
+
+	for _, param := range fn.Params {
+		name := r.text(param.Name)
+		if prev, ok := r.symbols.Declare(name, param); !ok {
+			r.errorAt(erremitter.CodeDuplicateParam, fmt.Sprintf("parameter '%s' is declared more than once", name), param.Name.Scope,
+				r.note("first declared here", r.nodeScope(prev)))
+		}
+	}
+
+	for _, stmt := range fn.Body.Stmts {
+		r.resolveStmt(stmt)
+	}
+
+	for _, let := range r.lets {
+		name := r.text(let.Name)
+		if !r.read[let] && !strings.HasPrefix(name, "_") {
+			r.warningAt(erremitter.CodeUnusedVariable, fmt.Sprintf("unused variable '%s'", name), let.Name.Scope,
+				help(fmt.Sprintf("if this is intentional, name it '_%s'", name)))
+		}
+	}
+}
+
+func (r *Resolver) resolveStmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.BlockStmt:
+		r.symbols.Enter()
+		for _, s := range stmt.Stmts {
+			r.resolveStmt(s)
+		}
+		r.symbols.Leave()
+	case *ast.LetStmt:
+		// The initializer is resolved first, so it still sees whatever the
+		// new variable shadows.
+		r.resolveExpr(stmt.Value)
+		name := r.text(stmt.Name)
+		r.lets = append(r.lets, stmt)
+		if prev, ok := r.symbols.Declare(name, stmt); !ok {
+			r.errorAt(erremitter.CodeRedeclaredVar, fmt.Sprintf("variable '%s' is already declared in this scope", name), stmt.Name.Scope,
+				r.note("previous declaration is here", prev.DeclName().Scope),
+				help("assign to it instead, or declare the new variable in a nested block to shadow it"))
+		}
+	case *ast.IfStmt:
+		r.resolveExpr(stmt.Cond)
+		r.resolveStmt(stmt.Then)
+		if stmt.Else != nil {
+			r.resolveStmt(stmt.Else)
+		}
+	case *ast.WhileStmt:
+		r.resolveExpr(stmt.Cond)
+		r.resolveLoopBody(stmt, stmt.Body)
+	case *ast.LoopStmt:
+		r.resolveLoopBody(stmt, stmt.Body)
+	case *ast.ForStmt:
+		r.resolveExpr(stmt.Start)
+		r.resolveExpr(stmt.End)
+		// The loop variable is only visible in the body.
+		r.symbols.Enter()
+		r.symbols.Declare(r.text(stmt.Var), stmt)
+		r.resolveLoopBody(stmt, stmt.Body)
+		r.symbols.Leave()
+	case *ast.BreakStmt:
+		stmt.Target = r.jumpTarget("break", stmt.Break, stmt.Label)
+	case *ast.ContinueStmt:
+		stmt.Target = r.jumpTarget("continue", stmt.Continue, stmt.Label)
+	case *ast.ExprStmt:
+		if assign, ok := stmt.Expr.(*ast.AssignExpr); ok {
+			r.resolveAssign(assign)
+		} else {
+			r.resolveExpr(stmt.Expr)
+		}
+	case *ast.ReturnStmt:
+		r.resolveExpr(stmt.Expr)
+	}
+}
+
+func (r *Resolver) resolveLoopBody(loop ast.Loop, body *ast.BlockStmt) {
+	r.loops = append(r.loops, loop)
+	r.resolveStmt(body)
+	r.loops = r.loops[:len(r.loops)-1]
+}
+
+// jumpTarget finds the loop left or continued by a 'break' or 'continue'
+// with the given keyword token and optional label.
+func (r *Resolver) jumpTarget(keyword string, tok token.Token, label *token.Token) ast.Loop {
+	if len(r.loops) == 0 {
+		r.errorAt(erremitter.CodeJumpOutsideLoop, fmt.Sprintf("'%s' outside of a loop", keyword), tok.Scope)
+		return nil
+	}
+	if label == nil {
+		return r.loops[len(r.loops)-1]
+	}
+
+	name := r.text(*label)
+	for i := len(r.loops) - 1; i >= 0; i-- {
+		if loopLabel := r.loops[i].LoopLabel(); loopLabel != nil && r.text(*loopLabel) == name {
+			return r.loops[i]
+		}
+	}
+	r.errorAt(erremitter.CodeUndeclaredLabel, fmt.Sprintf("no enclosing loop is labeled '%s'", name), label.Scope)
+	return nil
+}
+
+// resolveAssign resolves an assignment statement and checks that its target
+// can be assigned to.
+func (r *Resolver) resolveAssign(expr *ast.AssignExpr) {
+	// Assigning to a variable doesn't read it.
+	if v, ok := expr.Target.(*ast.VarExpr); ok {
+		r.resolveVar(v, false)
+	} else {
+		r.resolveExpr(expr.Target)
+	}
+	r.resolveExpr(expr.Value)
+	if !isPlace(expr.Target) {
+		r.errorAt(erremitter.CodeInvalidAssignTarget,
+			fmt.Sprintf("cannot assign to this expression, the left side of '%s' must be a variable", r.text(expr.Op)),
+			r.nodeScope(expr.Target))
+	} else if v, ok := expr.Target.(*ast.VarExpr); ok {
+		if loop, ok := v.Decl.(*ast.ForStmt); ok {
+			r.errorAt(erremitter.CodeAssignLoopVar,
+				fmt.Sprintf("cannot assign to loop variable '%s', it is advanced by the loop", r.text(v.Name)), r.nodeScope(expr),
+				r.note("declared by this loop", loop.Var.Scope),
+				help("copy it into a new variable with 'let' to change it"))
+		}
+	}
+}
+
+func (r *Resolver) resolveExpr(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.UnaryExpr:
+		r.resolveExpr(expr.Rhs)
+	case *ast.BinaryExpr:
+		r.resolveExpr(expr.Lhs)
+		r.resolveExpr(expr.Rhs)
+	case *ast.AssignExpr:
+		// Statements run in order, but C doesn't order an assignment
+		// against the other operands of the expression around it.
+		r.errorAt(erremitter.CodeAssignInExpr, "assignments can only be used as statements", r.nodeScope(expr),
+			help(fmt.Sprintf("move the '%s' into a statement of its own before this one", r.text(expr.Op))))
+		r.resolveAssign(expr)
+	case *ast.CallExpr:
+		r.resolveCall(expr)
+	case *ast.VarExpr:
+		r.resolveVar(expr, true)
+	case *ast.IfExpr:
+		r.resolveExpr(expr.Cond)
+		r.resolveExpr(expr.Then)
+		r.resolveExpr(expr.Else)
+	case *ast.CastExpr:
+		r.resolveExpr(expr.Expr)
+	}
+}
+
+// resolveVar binds v to its declaration. read tells whether the value of
+// the variable is used, as opposed to only assigned to.
+func (r *Resolver) resolveVar(v *ast.VarExpr, read bool) {
+	name := r.text(v.Name)
+	if decl, ok := r.symbols.Lookup(name); ok {
+		v.Decl = decl
+		if read {
+			r.read[decl] = true
+		}
+		return
+	}
+
+	if _, ok := r.funcs[name]; ok {
+		r.errorAt(erremitter.CodeFuncAsValue, fmt.Sprintf("function '%s' can only be called", name), v.Name.Scope,
+			help(fmt.Sprintf("call it with '%s(...)'", name)))
+		return
+	}
+	r.errorAt(erremitter.CodeUndeclaredVar, fmt.Sprintf("undeclared variable '%s'", name), v.Name.Scope)
+}
+
+func (r *Resolver) resolveCall(call *ast.CallExpr) {
+	for _, arg := range call.Args {
+		r.resolveExpr(arg)
+	}
+
+	callee, ok := call.Callee.(*ast.VarExpr)
+	if !ok {
+		r.errorAt(erremitter.CodeNotCallable, "only functions can be called", r.nodeScope(call.Callee))
+		return
+	}
+
+	name := r.text(callee.Name)
+	fn, ok := r.funcs[name]
+	if !ok {
+		r.errorAt(erremitter.CodeUndefinedFunc, fmt.Sprintf("call to undefined function '%s'", name), callee.Name.Scope)
+		return
+	}
+	call.Func = fn
+
+	if len(call.Args) != len(fn.Params) {
+		r.errorAt(erremitter.CodeArgCount,
+			fmt.Sprintf("function '%s' takes %d argument(s) but %d were supplied", name, len(fn.Params), len(call.Args)),
+			r.nodeScope(call), r.note(fmt.Sprintf("'%s' is defined here", name), fn.Name.Scope))
+	}
+}
+
+// isPlace reports whether expr denotes a memory location that can be
+// assigned to, as opposed to a temporary value.
+func isPlace(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.VarExpr:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package sema_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/erremitter"
+	"github.com/Mixturka/rc/internal/lexer"
+	"github.com/Mixturka/rc/internal/parser"
+	"github.com/Mixturka/rc/internal/pkg/source"
+	"github.com/Mixturka/rc/internal/sema"
+)
+
+// resolve returns the messages of the errors found in src.
+func resolve(t *testing.T, src string) []string {
+	t.Helper()
+	return diagnostics(t, src, erremitter.Error)
+}
+
+func diagnostics(t *testing.T, src string, severity erremitter.ErrType) []string {
+	t.Helper()
+
+	em := erremitter.NewErrEmitter()
+	l := lexer.NewLexer([]rune(src), &em)
+	toks := l.Tokenize()
+	p := parser.NewParser(toks, &em, []rune(src))
+	program := p.Parse()
+
+	r := sema.NewResolver(&em, source.NewFile(0, "test.rc", []rune(src)))
+	r.Resolve(program)
+
+	var messages []string
+	for _, e := range em.Errors() {
+		if e.Severity == severity {
+			messages = append(messages, e.Message)
+		}
+	}
+	return messages
+}
+
+func TestResolveCallsInAnyOrder(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { return add(1, 2); } fn add(a: i32, b: i32) -> i32 { return a + b; }")
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestResolveCallArity(t *testing.T) {
+	errs := resolve(t, "fn add(a: i32, b: i32) -> i32 { return a + b; } fn main() -> i32 { return add(1); }")
+	expected := []string{"function 'add' takes 2 argument(s) but 1 were supplied"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveUndefinedFunction(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { return foo(); }")
+	expected := []string{"call to undefined function 'foo'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveDuplicateFunction(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { return 0; } fn main() -> i32 { return 1; }")
+	expected := []string{"function 'main' is defined more than once"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveDuplicateParameter(t *testing.T) {
+	errs := resolve(t, "fn f(a: i32, a: i32) -> i32 { return 0; } fn main() -> i32 { { return 0; } }")
+	expected := []string{"parameter 'a' is declared more than once"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveUndeclaredVariable(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { { let x = 1; } return x; }")
+	expected := []string{"undeclared variable 'x'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveShadowedVariable(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { let x: i32 = 1; { let x = x + 1; return x; } }")
+	if len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestResolveInitializerDoesNotSeeItself(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { let x = x; return x; }")
+	expected := []string{"undeclared variable 'x'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveRedeclaredVariable(t *testing.T) {
+	errs := resolve(t, "fn main(a: i32) -> i32 { let a = 1; let b = 2; let b = 3; return b; }")
+	expected := []string{"variable 'a' is already declared in this scope", "variable 'b' is already declared in this scope"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveAssignToRvalue(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { let x = 0; x = 1; x += 2; 1 = 2; x + 1 -= 3; return x; }")
+	expected := []string{
+		"cannot assign to this expression, the left side of '=' must be a variable",
+		"cannot assign to this expression, the left side of '-=' must be a variable",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveAssignInExpression(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { let x = 0; let a = 0; x += (x = 5); a = x = 1; let y = x + (x = 2); return a + y + (x = 3); }")
+	expected := []string{
+		"assignments can only be used as statements",
+		"assignments can only be used as statements",
+		"assignments can only be used as statements",
+		"assignments can only be used as statements",
+	}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveJumpOutsideLoop(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { break; if 1 { continue; } loop { break; } return 0; }")
+	expected := []string{"'break' outside of a loop", "'continue' outside of a loop"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveJumpLabels(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { outer: loop { while 1 { continue outer; break inner; } } inner: loop { break inner; } return 0; }")
+	expected := []string{"no enclosing loop is labeled 'inner'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveForLoopVariableScope(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { for i in 0..10 { let x = i; } return i; }")
+	expected := []string{"undeclared variable 'i'"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveAssignToLoopVariable(t *testing.T) {
+	errs := resolve(t, "fn main() -> i32 { for i in 0..=10 { i += 1; } return 0; }")
+	expected := []string{"cannot assign to loop variable 'i', it is advanced by the loop"}
+	if !slices.Equal(errs, expected) {
+		t.Errorf("Expected: %v, got %v", expected, errs)
+	}
+}
+
+func TestResolveUnusedVariable(t *testing.T) {
+	warnings := diagnostics(t, "fn main(p: i32) -> i32 { let a = 1; let b = 2; let c = 3; let _d = 4; a = 5; c += 1; return b; }",
+		erremitter.Warning)
+	expected := []string{"unused variable 'a'", "unused variable 'c'"}
+	if !slices.Equal(warnings, expected) {
+		t.Errorf("Expected: %v, got %v", expected, warnings)
+	}
+}
@@ -0,0 +1,112 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Type is one of the built-in types of rc.
+type Type int
+
+const (
+	// Invalid is the type of expressions that already have an error
+	// reported. Checks involving it are skipped, so one mistake isn't
+	// reported over and over.
+	Invalid Type = iota
+	I8
+	I16
+	I32
+	I64
+	Isize
+	U8
+	U16
+	U32
+	U64
+	Usize
+	// Bool is the type of 'true' and 'false', comparisons and logical
+	// operators. It is not an integer.
+	Bool
+)
+
+var typeNames = [...]string{
+	Invalid: "<invalid>",
+	I8:      "i8",
+	I16:     "i16",
+	I32:     "i32",
+	I64:     "i64",
+	Isize:   "isize",
+	U8:      "u8",
+	U16:     "u16",
+	U32:     "u32",
+	U64:     "u64",
+	Usize:   "usize",
+	Bool:    "bool",
+}
+
+func (t Type) String() string {
+	if int(t) < 0 || int(t) >= len(typeNames) {
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+
+	return typeNames[t]
+}
+
+// Lookup returns the built-in type spelled name.
+func Lookup(name string) (Type, bool) {
+	for t, typeName := range typeNames {
+		if Type(t) != Invalid && typeName == name {
+			return Type(t), true
+		}
+	}
+
+	return Invalid, false
+}
+
+func (t Type) IsInteger() bool {
+	return t >= I8 && t <= Usize
+}
+
+func (t Type) IsSigned() bool {
+	return t >= I8 && t <= Isize
+}
+
+// Bits returns the width of an integer type. rc only targets 64-bit
+// platforms, so isize and usize are as wide as i64 and u64.
+func (t Type) Bits() int {
+	switch t {
+	case I8, U8:
+		return 8
+	case I16, U16:
+		return 16
+	case I32, U32:
+		return 32
+	case I64, Isize, U64, Usize:
+		return 64
+	}
+
+	return 0
+}
+
+// Min returns the smallest value of an integer type.
+func (t Type) Min() *big.Int {
+	if !t.IsSigned() {
+		return big.NewInt(0)
+	}
+
+	return new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(t.Bits()-1)))
+}
+
+// Max returns the largest value of an integer type.
+func (t Type) Max() *big.Int {
+	bits := t.Bits()
+	if t.IsSigned() {
+		bits--
+	}
+
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1))
+}
+
+// Fits reports whether v is a value of the integer type t.
+func (t Type) Fits(v *big.Int) bool {
+	return t.IsInteger() && v.Cmp(t.Min()) >= 0 && v.Cmp(t.Max()) <= 0
+}
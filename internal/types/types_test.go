@@ -0,0 +1,51 @@
+package types_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Mixturka/rc/internal/types"
+)
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"i8", "i16", "i32", "i64", "isize", "u8", "u16", "u32", "u64", "usize"} {
+		typ, ok := types.Lookup(name)
+		if !ok || typ.String() != name {
+			t.Errorf("Expected: %v, got %v", name, typ)
+		}
+	}
+	if typ, ok := types.Lookup("int"); ok {
+		t.Errorf("Expected 'int' not to be a type, got %v", typ)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	tests := []struct {
+		typ      types.Type
+		min, max string
+	}{
+		{types.I8, "-128", "127"},
+		{types.U8, "0", "255"},
+		{types.I32, "-2147483648", "2147483647"},
+		{types.U32, "0", "4294967295"},
+		{types.I64, "-9223372036854775808", "9223372036854775807"},
+		{types.Usize, "0", "18446744073709551615"},
+	}
+	for _, test := range tests {
+		if got := test.typ.Min().String(); got != test.min {
+			t.Errorf("Expected: %v as the minimum of %v, got %v", test.min, test.typ, got)
+		}
+		if got := test.typ.Max().String(); got != test.max {
+			t.Errorf("Expected: %v as the maximum of %v, got %v", test.max, test.typ, got)
+		}
+	}
+}
+
+func TestFits(t *testing.T) {
+	if !types.U8.Fits(big.NewInt(255)) || types.U8.Fits(big.NewInt(256)) || types.U8.Fits(big.NewInt(-1)) {
+		t.Errorf("Expected u8 to hold exactly 0 to 255")
+	}
+	if types.Invalid.Fits(big.NewInt(0)) {
+		t.Errorf("Expected no value to fit the invalid type")
+	}
+}